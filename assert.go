@@ -0,0 +1,27 @@
+package memnet
+
+import (
+	"net"
+	"testing"
+)
+
+// AssertDrained fails the test if c has unread bytes from its peer, which
+// shows a protocol where one side sends more than the other consumes.
+// The read buffer of a conn wrapped by NewBufferedConn counts as well.
+// Conns which don't come from this package are never reported.
+func AssertDrained(t testing.TB, c net.Conn) {
+	t.Helper()
+
+	n := 0
+	if bc, ok := c.(*BufferedConn); ok {
+		n += bc.Buffered()
+	}
+
+	if mc, ok := unwrapConn(c); ok {
+		n += mc.Buffered()
+	}
+
+	if n > 0 {
+		t.Errorf("memnet: %d bytes from %v were left unread", n, c.RemoteAddr())
+	}
+}
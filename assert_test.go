@@ -0,0 +1,107 @@
+package memnet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+// recorderTB records the failures of the assertions under test.
+type recorderTB struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recorderTB) Helper() {}
+
+func (r *recorderTB) Errorf(format string, args ...interface{}) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func TestAssertDrained(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := local.Write([]byte("ping")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if _, err := io.ReadFull(remote, make([]byte, 4)); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	rec := &recorderTB{}
+	AssertDrained(rec, remote)
+	if len(rec.errs) != 0 {
+		t.Fatalf("AssertDrained after a clean exchange failed with %q", rec.errs)
+	}
+
+	// A leftover write, partly read ahead by a buffered reader
+	if _, err := local.Write([]byte("extra")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	bc := NewBufferedConn(remote, 16)
+	if _, err := bc.Peek(1); err != nil {
+		t.Fatalf("bc.Peek = _, %v", err)
+	}
+
+	AssertDrained(rec, bc)
+	if len(rec.errs) != 1 {
+		t.Fatalf("AssertDrained with a leftover write failed %d times, want once", len(rec.errs))
+	}
+
+	want := fmt.Sprintf("memnet: 5 bytes from %v were left unread", remote.RemoteAddr())
+	if rec.errs[0] != want {
+		t.Fatalf("AssertDrained failed with %q, want %q", rec.errs[0], want)
+	}
+}
+
+func TestAssertDrainedCompressedWhileReading(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	local, err := ln.DialContext(context.Background(), WithTransparentCompression())
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	// A Read waits for the next frame while the drain is asserted
+	readCh := doRead(remote, make([]byte, 4))
+	waitWaiters(t, remote.(*conn).r.(*ringBuff), 1, 0)
+
+	done := make(chan []string, 1)
+	go func() {
+		rec := &recorderTB{}
+		AssertDrained(rec, remote)
+		done <- rec.errs
+	}()
+
+	select {
+	case errs := <-done:
+		if len(errs) != 0 {
+			t.Fatalf("AssertDrained on a drained conn failed with %q", errs)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("AssertDrained blocked on the pending Read")
+	}
+
+	if _, err := local.Write([]byte("ping")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+	if result := <-readCh; result.n != 4 || result.err != nil {
+		t.Fatalf("remote.Read = %d, %v, want 4, nil", result.n, result.err)
+	}
+}
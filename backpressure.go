@@ -0,0 +1,36 @@
+package memnet
+
+// OnBackpressure registers f to be called when a write has to wait
+// because the buffer towards the peer is full, and again once a write
+// finds room after that, so adaptive writers can slow down. f gets the
+// number of buffered bytes and the capacity of the buffer. It is called
+// by the writing goroutine without any lock held, so it may use the
+// conn. A nil f stops the calls. Unbuffered conns never call it.
+func (c *conn) OnBackpressure(f func(buffered, cap int)) {
+	rb := c.w.(*ringBuff)
+
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rb.onBackpressure = f
+	rb.saturated = false
+}
+
+// backpressure calls the OnBackpressure callback when the ring turned
+// full or got room again since the last call. It reports whether it
+// released rb.mu to do so, the caller has to check the ring's state
+// again then. rb.mu must be held.
+func (rb *ringBuff) backpressure(full bool) bool {
+	if rb.onBackpressure == nil || rb.saturated == full {
+		return false
+	}
+
+	rb.saturated = full
+	f, buffered := rb.onBackpressure, rb.buffered()
+
+	rb.mu.Unlock()
+	defer rb.mu.Lock()
+
+	f(buffered, cap(rb.buff))
+	return true
+}
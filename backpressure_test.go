@@ -0,0 +1,64 @@
+package memnet
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestConnOnBackpressure(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	type event struct{ buffered, cap int }
+	events := make(chan event, 16)
+	local.(*conn).OnBackpressure(func(buffered, cap int) {
+		select {
+		case events <- event{buffered, cap}:
+		default:
+		}
+	})
+
+	// Fits into the ring, no pressure yet
+	if _, err := local.Write([]byte("0123")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	capacity := remote.(*conn).Cap()
+	input := []byte("0123456789abcdefghij")
+	writeCh := doWrite(local, input)
+	select {
+	case e := <-events:
+		if e != (event{capacity, capacity}) {
+			t.Fatalf("saturation = %+v, want %+v", e, event{capacity, capacity})
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("OnBackpressure wasn't called on saturation")
+	}
+
+	// A slow reader makes room
+	time.Sleep(20 * time.Millisecond)
+	output := make([]byte, 4+len(input))
+	if _, err := io.ReadFull(remote, output[:4]); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	select {
+	case e := <-events:
+		if e.buffered >= e.cap || e.cap != capacity {
+			t.Fatalf("recovery = %+v, want room in a ring of %d", e, capacity)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("OnBackpressure wasn't called on recovery")
+	}
+
+	if _, err := io.ReadFull(remote, output[4:]); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	if res := <-writeCh; res.err != nil {
+		t.Fatalf(errWriteLocalConn, res.err.Error())
+	}
+}
@@ -0,0 +1,128 @@
+package memnet
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// Benchmark measures the round-trip throughput of a conn pair with
+// buffers of bufSize bytes: b.N messages of msgSize bytes are written by
+// the dialer, echoed back by the accepted end and read in full again.
+// The MB/s reported by the benchmark counts both directions.
+//
+// It is meant to be called from a Benchmark function to compare buffer
+// and message sizes without rewriting the set up.
+func Benchmark(b *testing.B, bufSize, msgSize int) {
+	b.Helper()
+
+	ln, err := Listen(1, bufSize, "memnet.bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer ln.Close()
+
+	local, err := ln.Dial()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer local.Close()
+
+	remote, err := ln.Accept()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer remote.Close()
+
+	// Echo till the dialer closes
+	go io.Copy(remote, remote)
+
+	msg := make([]byte, msgSize)
+	resp := make([]byte, msgSize)
+
+	b.SetBytes(int64(2 * msgSize))
+	b.ResetTimer()
+
+	// The writes are done aside so that a message bigger than the
+	// buffers can't stall the echo
+	werr := make(chan error, 1)
+	go func() {
+		for i := 0; i < b.N; i++ {
+			if _, err := local.Write(msg); err != nil {
+				werr <- err
+				return
+			}
+		}
+		werr <- nil
+	}()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := io.ReadFull(local, resp); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.StopTimer()
+
+	if err := <-werr; err != nil {
+		b.Fatal(err)
+	}
+}
+
+// MeasureThroughput pumps total bytes through a conn pair with buffers of
+// bufSize bytes, dialed with opts, and returns the rate at which the
+// accepted end read them, in bytes per second. It is a quick check of
+// what the simulated network conditions of opts, like WithLatency and
+// WithBandwidth, amount to. It returns 0 if the transfer fails.
+func MeasureThroughput(bufSize, total int, opts ...DialOption) float64 {
+	ln, err := Listen(1, bufSize, "memnet.measure")
+	if err != nil {
+		return 0
+	}
+	defer ln.Close()
+
+	local, err := ln.DialContext(context.Background(), opts...)
+	if err != nil {
+		return 0
+	}
+	defer local.Close()
+
+	remote, err := ln.Accept()
+	if err != nil {
+		return 0
+	}
+	defer remote.Close()
+
+	start := time.Now()
+
+	read := make(chan int64, 1)
+	go func() {
+		n, _ := io.Copy(ioutil.Discard, remote)
+		read <- n
+	}()
+
+	buf := make([]byte, copyBufSize)
+	for left := total; left > 0; {
+		p := buf
+		if left < len(p) {
+			p = p[:left]
+		}
+
+		n, err := local.Write(p)
+		left -= n
+		if err != nil {
+			return 0
+		}
+	}
+	local.(*conn).CloseWrite()
+
+	n := <-read
+	elapsed := time.Since(start)
+
+	if n != int64(total) || elapsed <= 0 {
+		return 0
+	}
+	return float64(n) / elapsed.Seconds()
+}
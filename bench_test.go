@@ -0,0 +1,38 @@
+package memnet
+
+import "testing"
+
+func BenchmarkRoundTrip(b *testing.B) {
+	for _, bc := range []struct {
+		name             string
+		bufSize, msgSize int
+	}{
+		{"buf1k/msg64", 1 << 10, 64},
+		{"buf1k/msg4k", 1 << 10, 4 << 10},
+		{"buf64k/msg4k", 64 << 10, 4 << 10},
+		{"unbuffered/msg4k", 0, 4 << 10},
+	} {
+		bc := bc
+		b.Run(bc.name, func(b *testing.B) {
+			Benchmark(b, bc.bufSize, bc.msgSize)
+		})
+	}
+}
+
+func TestMeasureThroughputBandwidth(t *testing.T) {
+	const bw = 1 << 20
+
+	rate := MeasureThroughput(1<<10, 100<<10, WithBandwidth(bw))
+	if rate <= 0 {
+		t.Fatal("MeasureThroughput failed")
+	}
+
+	// Sleeping only ever makes it slower than the cap
+	if rate > 1.05*bw || rate < 0.5*bw {
+		t.Fatalf("MeasureThroughput = %.0f B/s, want about %d B/s", rate, bw)
+	}
+
+	if free := MeasureThroughput(1<<10, 100<<10); free < 2*bw {
+		t.Fatalf("MeasureThroughput = %.0f B/s without a throttle, want more than %d B/s", free, 2*bw)
+	}
+}
@@ -0,0 +1,38 @@
+package memnet
+
+import (
+	"bufio"
+	"net"
+)
+
+// BufferedConn is a net.Conn which reads through a bufio.Reader, so the
+// code which peeks at a conn can still hand it on as a net.Conn, and
+// AssertDrained and SamePeer see through it. Deadlines go straight to
+// the wrapped conn: bytes already buffered are returned past a deadline,
+// and a read which has to wait for the conn fails with the conn's
+// timeout error.
+type BufferedConn struct {
+	net.Conn
+	br *bufio.Reader
+}
+
+// NewBufferedConn wraps c with a read buffer of size bytes.
+func NewBufferedConn(c net.Conn, size int) *BufferedConn {
+	return &BufferedConn{c, bufio.NewReaderSize(c, size)}
+}
+
+func (bc *BufferedConn) Read(p []byte) (int, error) {
+	return bc.br.Read(p)
+}
+
+// Peek returns the next n bytes without consuming them, see
+// bufio.Reader.Peek.
+func (bc *BufferedConn) Peek(n int) ([]byte, error) {
+	return bc.br.Peek(n)
+}
+
+// Buffered returns the number of bytes which can be read from the read
+// buffer without touching the conn.
+func (bc *BufferedConn) Buffered() int {
+	return bc.br.Buffered()
+}
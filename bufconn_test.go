@@ -0,0 +1,52 @@
+package memnet
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+var _ net.Conn = (*BufferedConn)(nil)
+
+func TestBufferedConnDeadline(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	bc := NewBufferedConn(remote, 16)
+
+	if _, err := local.Write([]byte("abcdef")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	p := make([]byte, 2)
+	if n, err := bc.Read(p); n != 2 || err != nil {
+		t.Fatalf("bc.Read = %d, %v, want %d, nil", n, err, 2)
+	}
+
+	bc.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	time.Sleep(30 * time.Millisecond)
+
+	// The rest was buffered before the deadline
+	if n, err := bc.Read(make([]byte, 8)); n != 4 || err != nil {
+		t.Fatalf("bc.Read = %d, %v, want %d, nil", n, err, 4)
+	}
+
+	if _, err := bc.Read(p); err != errTimeout {
+		t.Fatalf("bc.Read = _, %v, want %v", err, errTimeout)
+	}
+
+	if _, err := bc.Peek(1); err != errTimeout {
+		t.Fatalf("bc.Peek = _, %v, want %v", err, errTimeout)
+	}
+
+	bc.SetReadDeadline(time.Time{})
+	if _, err := local.Write([]byte("gh")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if n, err := bc.Read(p); n != 2 || err != nil || string(p) != "gh" {
+		t.Fatalf("bc.Read = %d, %v, want %d, nil", n, err, 2)
+	}
+}
@@ -0,0 +1,80 @@
+package memnet
+
+import "context"
+
+// Buffer is a bounded, blocking and thread-safe byte pipe. It is the same
+// ring buffer which carries each direction of a memnet conn.
+type Buffer struct {
+	rb *ringBuff
+}
+
+// NewBuffer returns an empty *Buffer which holds up to size bytes.
+func NewBuffer(size int) *Buffer {
+	return &Buffer{newRingBuff(size)}
+}
+
+// Read blocks until there are buffered bytes to read.
+func (b *Buffer) Read(p []byte) (int, error) {
+	return b.rb.Read(p)
+}
+
+// Write blocks until all of p is buffered.
+func (b *Buffer) Write(p []byte) (int, error) {
+	return b.rb.Write(p)
+}
+
+// ReadCtx is like Read but returns ctx.Err() if ctx is done while it is
+// blocked waiting for bytes.
+func (b *Buffer) ReadCtx(ctx context.Context, p []byte) (int, error) {
+	return b.rb.readCtx(ctx, p)
+}
+
+// WriteCtx is like Write but returns ctx.Err() along with the number of
+// bytes already written if ctx is done while it is blocked waiting for
+// room.
+func (b *Buffer) WriteCtx(ctx context.Context, p []byte) (int, error) {
+	return b.rb.writeCtx(ctx, p)
+}
+
+// Close fails all pending and future reads and writes with
+// io.ErrClosedPipe, discarding the buffered bytes.
+func (b *Buffer) Close() error {
+	return b.rb.Close()
+}
+
+// CloseWrite fails future writes, readers get io.EOF once they drain
+// the buffered bytes.
+func (b *Buffer) CloseWrite() error {
+	return b.rb.closeWrite(nil)
+}
+
+// Buffered returns the number of bytes which can be read.
+func (b *Buffer) Buffered() int {
+	b.rb.mu.Lock()
+	defer b.rb.mu.Unlock()
+
+	return b.rb.buffered()
+}
+
+// Snapshot returns a copy of the buffered bytes without consuming them,
+// a later Read still returns them.
+func (b *Buffer) Snapshot() []byte {
+	b.rb.mu.Lock()
+	defer b.rb.mu.Unlock()
+
+	return b.rb.snapshot()
+}
+
+// Available returns the number of bytes which can be written without
+// blocking.
+func (b *Buffer) Available() int {
+	b.rb.mu.Lock()
+	defer b.rb.mu.Unlock()
+
+	return cap(b.rb.buff) - b.rb.buffered()
+}
+
+// Cap returns the capacity of the buffer.
+func (b *Buffer) Cap() int {
+	return cap(b.rb.buff)
+}
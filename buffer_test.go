@@ -0,0 +1,241 @@
+package memnet
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+var _ io.ReadWriteCloser = (*Buffer)(nil)
+
+func TestBuffer(t *testing.T) {
+	b := NewBuffer(10)
+	err := doReadWrite(b)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestBufferClosed(t *testing.T) {
+	b := NewBuffer(10)
+
+	b.Close()
+	if _, err := b.Write(nil); err != io.ErrClosedPipe {
+		t.Fatalf("b.Write = _, %v; want _, %v", err, io.ErrClosedPipe)
+	}
+
+	if _, err := b.Read(nil); err != io.ErrClosedPipe {
+		t.Fatalf("b.Read = _, %v; want _, %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestBufferCloseWrite(t *testing.T) {
+	b := NewBuffer(10)
+
+	b.Write([]byte("abc"))
+	b.CloseWrite()
+
+	if _, err := b.Write([]byte("d")); err != io.ErrClosedPipe {
+		t.Fatalf("b.Write = _, %v; want _, %v", err, io.ErrClosedPipe)
+	}
+
+	p := make([]byte, 10)
+	if n, err := b.Read(p); n != 3 || err != nil {
+		t.Fatalf("b.Read = %d, %v; want %d, nil", n, err, 3)
+	}
+
+	if _, err := b.Read(p); err != io.EOF {
+		t.Fatalf("b.Read = _, %v; want _, %v", err, io.EOF)
+	}
+}
+
+func TestBufferBuffered(t *testing.T) {
+	b := NewBuffer(10)
+
+	check := func(buffered int) {
+		t.Helper()
+		if b.Buffered() != buffered || b.Available() != b.Cap()-buffered {
+			t.Fatalf("b.Buffered(), b.Available() = %d, %d; want %d, %d",
+				b.Buffered(), b.Available(), buffered, b.Cap()-buffered)
+		}
+	}
+
+	if b.Cap() != 10 {
+		t.Fatalf("b.Cap() = %d; want %d", b.Cap(), 10)
+	}
+
+	check(0)
+
+	b.Write(make([]byte, 7))
+	check(7)
+
+	b.Read(make([]byte, 5))
+	check(2)
+
+	// Wrap around the end of the ring
+	b.Write(make([]byte, 8))
+	check(10)
+
+	b.Read(make([]byte, 4))
+	check(6)
+}
+
+func TestBufferSnapshot(t *testing.T) {
+	b := NewBuffer(10)
+
+	// Wrap the unread bytes around the end of the ring
+	b.Write([]byte("xxxxxx"))
+	b.Read(make([]byte, 6))
+	b.Write([]byte("snapshot"))
+
+	snap := b.Snapshot()
+	if string(snap) != "snapshot" {
+		t.Fatalf("b.Snapshot() = %q; want %q", snap, "snapshot")
+	}
+
+	// The snapshot is a copy
+	snap[0] = 'S'
+
+	output := make([]byte, 8)
+	if _, err := io.ReadFull(b, output); err != nil {
+		t.Fatalf("io.ReadFull(b) = _, %v; want nil", err)
+	}
+
+	if string(output) != "snapshot" {
+		t.Fatalf(errIOMismatched, "snapshot", output)
+	}
+
+	if snap := b.Snapshot(); len(snap) != 0 {
+		t.Fatalf("b.Snapshot() = %q; want empty", snap)
+	}
+}
+
+func TestBufferMarshalState(t *testing.T) {
+	b := NewBuffer(10)
+
+	// Leave the unread bytes wrapped around the end of the ring
+	b.Write([]byte("xxxxxx"))
+	b.Read(make([]byte, 6))
+	b.Write([]byte("stalled!"))
+	b.CloseWrite()
+
+	state, err := b.MarshalState()
+	if err != nil {
+		t.Fatalf("b.MarshalState() = _, %v; want nil", err)
+	}
+
+	readAll := func(b *Buffer) string {
+		t.Helper()
+		var out []byte
+		for {
+			p := make([]byte, 3)
+			n, err := b.Read(p)
+			out = append(out, p[:n]...)
+			if err == io.EOF {
+				return string(out)
+			}
+			if err != nil {
+				t.Fatalf("b.Read = _, %v; want nil or %v", err, io.EOF)
+			}
+		}
+	}
+
+	if got := readAll(b); got != "stalled!" {
+		t.Fatalf("read %q; want %q", got, "stalled!")
+	}
+
+	// Restoring replays the reads, into a buffer of another size too
+	for _, rb := range []*Buffer{b, NewBuffer(4)} {
+		if err := rb.UnmarshalState(state); err != nil {
+			t.Fatalf("UnmarshalState = %v; want nil", err)
+		}
+
+		if rb.Cap() != 10 || rb.Buffered() != 8 {
+			t.Fatalf("restored Cap(), Buffered() = %d, %d; want %d, %d", rb.Cap(), rb.Buffered(), 10, 8)
+		}
+
+		if got := readAll(rb); got != "stalled!" {
+			t.Fatalf("read %q after UnmarshalState; want %q", got, "stalled!")
+		}
+	}
+
+	// The closed flag comes along
+	b.Close()
+	state, _ = b.MarshalState()
+	restored := NewBuffer(10)
+	restored.UnmarshalState(state)
+	if _, err := restored.Write([]byte("a")); err != io.ErrClosedPipe {
+		t.Fatalf("restored.Write = _, %v; want %v", err, io.ErrClosedPipe)
+	}
+
+	for _, bad := range []string{`{"Cap":4,"R":1,"W":0,"Len":3,"Unread":"YQ=="}`, `{"Cap":4,"W":9}`, `nope`} {
+		if err := NewBuffer(4).UnmarshalState([]byte(bad)); err == nil {
+			t.Fatalf("UnmarshalState(%s) = nil; want an error", bad)
+		}
+	}
+}
+
+func TestBufferReadCtx(t *testing.T) {
+	b := NewBuffer(10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	if n, err := b.ReadCtx(ctx, make([]byte, 1)); n != 0 || err != context.Canceled {
+		t.Fatalf("b.ReadCtx = %d, %v; want 0, %v", n, err, context.Canceled)
+	}
+
+	// Buffered bytes are still returned after ctx is done
+	b.Write([]byte("ab"))
+	if n, err := b.ReadCtx(ctx, make([]byte, 2)); n != 2 || err != nil {
+		t.Fatalf("b.ReadCtx = %d, %v; want %d, nil", n, err, 2)
+	}
+}
+
+func TestBufferWriteCtx(t *testing.T) {
+	b := NewBuffer(10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	n, err := b.WriteCtx(ctx, make([]byte, 15))
+	if n != 10 || err != context.DeadlineExceeded {
+		t.Fatalf("b.WriteCtx = %d, %v; want %d, %v", n, err, 10, context.DeadlineExceeded)
+	}
+
+	if b.Buffered() != 10 {
+		t.Fatalf("b.Buffered() = %d; want %d", b.Buffered(), 10)
+	}
+}
+
+func TestBufferMarshalStateUnbuffered(t *testing.T) {
+	b := NewBuffer(0)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.Write([]byte("hello"))
+		done <- err
+	}()
+	waitWaiters(t, b.rb, 0, 1)
+
+	// The parked write stays with its writer
+	state, err := b.MarshalState()
+	if err != nil {
+		t.Fatalf("b.MarshalState() = _, %v; want nil", err)
+	}
+
+	restored := NewBuffer(0)
+	if err := restored.UnmarshalState(state); err != nil {
+		t.Fatalf("UnmarshalState(%s) = %v; want nil", state, err)
+	}
+
+	if restored.Cap() != 0 || restored.Buffered() != 0 {
+		t.Fatalf("restored Cap(), Buffered() = %d, %d; want 0, 0", restored.Cap(), restored.Buffered())
+	}
+
+	b.Close()
+	if err := <-done; err != io.ErrClosedPipe {
+		t.Fatalf("b.Write = _, %v; want %v", err, io.ErrClosedPipe)
+	}
+}
@@ -0,0 +1,84 @@
+package memnet
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+var errBadState = fmt.Errorf("malformed buffer state")
+
+// bufferState is the serialized form of a Buffer. Unread holds the unread
+// bytes of the ring in reading order, the cursors tell where they sit.
+type bufferState struct {
+	Cap         int
+	R, W, Len   int
+	Unread      []byte
+	Prio        []byte `json:",omitempty"`
+	Closed      bool   `json:",omitempty"`
+	WriteClosed bool   `json:",omitempty"`
+}
+
+// MarshalState serializes the cursors, the unread bytes and whether the
+// buffer is closed, so a test can snapshot a buffer and restore it with
+// UnmarshalState to replay what follows. The error a buffer was closed
+// with isn't kept, and neither is the write a buffer without capacity
+// is blocked on, which still belongs to its writer.
+func (b *Buffer) MarshalState() ([]byte, error) {
+	rb := b.rb
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	return json.Marshal(bufferState{
+		Cap:         cap(rb.buff),
+		R:           rb.r,
+		W:           rb.w,
+		Len:         len(rb.buff),
+		Unread:      rb.snapshot()[len(rb.prio)+len(rb.pending):],
+		Prio:        rb.prio,
+		Closed:      rb.closed,
+		WriteClosed: rb.writeClosed,
+	})
+}
+
+// UnmarshalState replaces the state of the buffer with one saved by
+// MarshalState, the capacity included. Blocked readers and writers see
+// the new state right away.
+func (b *Buffer) UnmarshalState(data []byte) error {
+	var st bufferState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return err
+	}
+
+	if st.Len < 0 || st.Len > st.Cap || st.R < 0 || st.R > st.Len ||
+		st.W < 0 || st.W >= st.Cap && st.W != 0 {
+		return errBadState
+	}
+
+	buff := make([]byte, st.Len, st.Cap)
+	n := copy(buff[st.R:], st.Unread)
+	if n < len(st.Unread) {
+		if st.W > st.R {
+			return errBadState
+		}
+		n += copy(buff[:st.W], st.Unread[n:])
+	}
+
+	// The cursors have to frame exactly the unread bytes
+	restored := &ringBuff{buff: buff, r: st.R, w: st.W}
+	if n != len(st.Unread) || restored.buffered() != n {
+		return errBadState
+	}
+
+	rb := b.rb
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rb.buff, rb.r, rb.w = buff, st.R, st.W
+	rb.prio = append([]byte(nil), st.Prio...)
+	rb.closed, rb.writeClosed = st.Closed, st.WriteClosed
+	rb.wrerr, rb.rsterr = nil, nil
+
+	rb.rdwait.Broadcast()
+	rb.wrwait.Broadcast()
+	return nil
+}
@@ -0,0 +1,32 @@
+package memnet
+
+import "time"
+
+// clock is the source of the time and the timers behind the simulated
+// timings, tests swap it for a fake one to fire them deterministically.
+type clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) stopper
+}
+
+// stopper is the part of *time.Timer the conns use.
+type stopper interface {
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) stopper {
+	return time.AfterFunc(d, f)
+}
+
+// withClock makes the listener's conns take their timers from c.
+func withClock(c clock) Option {
+	return func(l *Listener) {
+		l.clock = c
+	}
+}
@@ -0,0 +1,73 @@
+package memnet
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// fakeClock is a clock whose timers only fire when it is advanced.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Duration
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	c    *fakeClock
+	at   time.Duration
+	f    func()
+	done bool
+}
+
+// Now is the time the clock was advanced to, it starts at the Unix epoch.
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return time.Unix(0, 0).Add(c.now)
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) stopper {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{c: c, at: c.now + d, f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+
+	stopped := !t.done
+	t.done = true
+	return stopped
+}
+
+// Advance moves the clock forward by d and runs the timers which became
+// due, in the order of their deadlines.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now += d
+
+	var due, left []*fakeTimer
+	for _, t := range c.timers {
+		switch {
+		case t.done:
+		case t.at <= c.now:
+			t.done = true
+			due = append(due, t)
+		default:
+			left = append(left, t)
+		}
+	}
+	c.timers = left
+	c.mu.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].at < due[j].at })
+	for _, t := range due {
+		t.f()
+	}
+}
@@ -0,0 +1,42 @@
+package memnet
+
+import "io"
+
+// WithClosedError makes the listener's conns fail reads and writes with
+// err instead of io.ErrClosedPipe once they got closed, which tells a
+// memnet conn apart from other pipes in tests.
+func WithClosedError(err error) Option {
+	return func(l *Listener) {
+		l.closedErr = err
+	}
+}
+
+// WithEOFError makes the readers of the listener's conns get err instead
+// of io.EOF once the peer closed and they read everything, for protocols
+// which want a terminal error of their own. It is not used for failing
+// writes, err should differ from the one of WithClosedError.
+func WithEOFError(err error) Option {
+	return func(l *Listener) {
+		l.eofErr = err
+	}
+}
+
+// errClosedPipe is the error of I/O on a closed ring.
+func (rb *ringBuff) errClosedPipe() error {
+	if rb.rsterr != nil {
+		return rb.rsterr
+	}
+
+	if rb.closedErr != nil {
+		return rb.closedErr
+	}
+	return io.ErrClosedPipe
+}
+
+// errEOF is the error of a read on a drained ring whose writer closed.
+func (rb *ringBuff) errEOF() error {
+	if rb.eofErr != nil {
+		return rb.eofErr
+	}
+	return io.EOF
+}
@@ -0,0 +1,79 @@
+package memnet
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestWithClosedError(t *testing.T) {
+	errMemClosed := errors.New("memnet: closed")
+
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, WithClosedError(errMemClosed))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	local.Close()
+
+	if _, err := local.Write([]byte("a")); err != errMemClosed {
+		t.Fatalf("local.Write = _, %v, want %v", err, errMemClosed)
+	}
+
+	if _, err := local.Read(make([]byte, 1)); err != errMemClosed {
+		t.Fatalf("local.Read = _, %v, want %v", err, errMemClosed)
+	}
+
+	// The peer closed its reading side
+	if _, err := remote.Write([]byte("a")); err != errMemClosed {
+		t.Fatalf("remote.Write = _, %v, want %v", err, errMemClosed)
+	}
+}
+
+func TestWithEOFError(t *testing.T) {
+	errEnd := errors.New("memnet: end of stream")
+
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, WithEOFError(errEnd))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	if _, err := local.Write([]byte("ab")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+	local.Close()
+
+	p := make([]byte, 4)
+	if n, err := remote.Read(p); n != 2 || err != nil {
+		t.Fatalf("remote.Read = %d, %v, want %d, nil", n, err, 2)
+	}
+
+	if _, err := remote.Read(p); err != errEnd {
+		t.Fatalf("remote.Read = _, %v, want %v", err, errEnd)
+	}
+
+	// Failing writes still report the closed pipe
+	if _, err := remote.Write(p); err != io.ErrClosedPipe {
+		t.Fatalf("remote.Write = _, %v, want %v", err, io.ErrClosedPipe)
+	}
+}
@@ -0,0 +1,33 @@
+package memnet
+
+import "time"
+
+// WithClosePropagationDelay makes the end of the stream take d to reach
+// the peer when either end of the dialed conn is closed, like a FIN on a
+// slow link: the peer still reads the buffered bytes, but only sees
+// io.EOF, and PeerClosedWrite, once d passed. The closed end fails its
+// own reads and writes right away. An Abort isn't delayed.
+func WithClosePropagationDelay(d time.Duration) DialOption {
+	return func(o *dialOptions) { o.closeDelay = d }
+}
+
+// holdFin keeps the readers of the ring from seeing the end of the
+// stream for its close delay, it is called right before the closeWrite
+// of a Close.
+func (rb *ringBuff) holdFin() {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if rb.closeDelay <= 0 || rb.closed || rb.writeClosed {
+		return
+	}
+
+	rb.finHeld = true
+	rb.clock.AfterFunc(rb.closeDelay, func() {
+		rb.mu.Lock()
+		defer rb.mu.Unlock()
+
+		rb.finHeld = false
+		rb.rdwait.Broadcast()
+	})
+}
@@ -0,0 +1,67 @@
+package memnet
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestClosePropagationDelay(t *testing.T) {
+	clk := &fakeClock{}
+
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, withClock(clk))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	const d = time.Second
+	local, err := ln.DialContext(context.Background(), WithClosePropagationDelay(d))
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	if _, err := local.Write([]byte("bye")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+	local.Close()
+
+	if _, err := local.Write([]byte("more")); err != io.ErrClosedPipe {
+		t.Fatalf("local.Write after Close = _, %v, want %v", err, io.ErrClosedPipe)
+	}
+
+	// The buffered bytes still arrive, the end of the stream waits
+	output := make([]byte, 8)
+	if n, err := remote.Read(output); n != 3 || err != nil {
+		t.Fatalf("remote.Read = %d, %v, want 3, nil", n, err)
+	}
+
+	readCh := doRead(remote, output)
+	waitWaiters(t, remote.(*conn).r.(*ringBuff), 1, 0)
+
+	clk.Advance(d / 2)
+	select {
+	case result := <-readCh:
+		t.Fatalf("remote.Read = %d, %v before the close delay passed", result.n, result.err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if remote.(*conn).PeerClosedWrite() {
+		t.Fatal("PeerClosedWrite() = true before the close delay passed")
+	}
+
+	clk.Advance(d / 2)
+	if result := <-readCh; result.n != 0 || result.err != io.EOF {
+		t.Fatalf("remote.Read = %d, %v, want 0, %v", result.n, result.err, io.EOF)
+	}
+
+	if !remote.(*conn).PeerClosedWrite() {
+		t.Fatal("PeerClosedWrite() = false after the close delay passed")
+	}
+}
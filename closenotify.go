@@ -0,0 +1,20 @@
+package memnet
+
+// CloseNotify returns a channel which is closed once the connection is
+// closed by either end, like http.CloseNotifier, so a server can select
+// on it alongside its own work. All calls on both ends return the same
+// channel.
+func (c *conn) CloseNotify() <-chan struct{} {
+	p := c.pair
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.notify == nil {
+		p.notify = make(chan struct{})
+		if p.isClosed {
+			close(p.notify)
+		}
+	}
+	return p.notify
+}
@@ -0,0 +1,48 @@
+package memnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConnCloseNotify(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	notify := remote.(*conn).CloseNotify()
+	if local.(*conn).CloseNotify() != notify {
+		t.Fatalf("the ends of a conn returned different CloseNotify channels")
+	}
+
+	select {
+	case <-notify:
+		t.Fatalf("CloseNotify fired on an open conn")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	local.Close()
+
+	select {
+	case <-notify:
+	case <-time.After(time.Second):
+		t.Fatalf("CloseNotify didn't fire when the peer closed")
+	}
+
+	// Closing the other end too doesn't close the channel again
+	remote.Close()
+
+	// Callers which come after the close find it closed too
+	local, _, err = memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	local.Close()
+	select {
+	case <-local.(*conn).CloseNotify():
+	default:
+		t.Fatalf("CloseNotify of a closed conn returned an open channel")
+	}
+}
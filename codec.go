@@ -0,0 +1,156 @@
+package memnet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// maxMessageSize bounds the frames the built-in codecs decode, so a
+// corrupt length can't make them allocate without bounds.
+const maxMessageSize = 16 << 20
+
+var errMessageType = fmt.Errorf("message type not supported by the codec")
+
+// Codec turns messages into bytes on a stream and back. Decode reads
+// exactly one message and stores it in the value msg points to, like
+// json.Unmarshal, it must not read past the end of the message.
+type Codec interface {
+	Encode(w io.Writer, msg interface{}) error
+	Decode(r io.Reader, msg interface{}) error
+}
+
+// LengthPrefixCodec frames []byte messages with their length as a 32 bit
+// big endian integer. Decode takes a *[]byte.
+var LengthPrefixCodec Codec = lengthPrefixCodec{}
+
+// JSONCodec sends messages as JSON, framed like LengthPrefixCodec.
+var JSONCodec Codec = jsonCodec{}
+
+type lengthPrefixCodec struct{}
+
+func (lengthPrefixCodec) Encode(w io.Writer, msg interface{}) error {
+	b, ok := msg.([]byte)
+	if !ok {
+		return errMessageType
+	}
+	return writeFrame(w, b)
+}
+
+func (lengthPrefixCodec) Decode(r io.Reader, msg interface{}) error {
+	p, ok := msg.(*[]byte)
+	if !ok {
+		return errMessageType
+	}
+
+	b, err := readFrame(r)
+	if err != nil {
+		return err
+	}
+
+	*p = b
+	return nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, msg interface{}) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return writeFrame(w, b)
+}
+
+func (jsonCodec) Decode(r io.Reader, msg interface{}) error {
+	b, err := readFrame(r)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, msg)
+}
+
+func writeFrame(w io.Writer, b []byte) error {
+	if len(b) > maxMessageSize {
+		return errTooLarge
+	}
+
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], uint32(len(b)))
+
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+
+	_, err := w.Write(b)
+	return err
+}
+
+// readFrame reads a frame of writeFrame. The stream ending before the
+// frame is complete is io.ErrUnexpectedEOF, ending at a frame boundary
+// io.EOF.
+func readFrame(r io.Reader) ([]byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n > maxMessageSize {
+		return nil, errTooLarge
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// MessageConn sends and receives discrete messages over a conn with a
+// Codec. Sends and receives are safe for concurrent use: every message
+// is encoded into a single Write, so messages of concurrent Sends never
+// interleave on the wire.
+type MessageConn struct {
+	net.Conn
+	codec Codec
+
+	rmu, wmu sync.Mutex
+	wbuf     bytes.Buffer
+}
+
+// NewMessageConn returns c sending and receiving messages with codec.
+func NewMessageConn(c net.Conn, codec Codec) *MessageConn {
+	return &MessageConn{Conn: c, codec: codec}
+}
+
+// Send encodes msg and writes it to the conn.
+func (mc *MessageConn) Send(msg interface{}) error {
+	mc.wmu.Lock()
+	defer mc.wmu.Unlock()
+
+	mc.wbuf.Reset()
+	if err := mc.codec.Encode(&mc.wbuf, msg); err != nil {
+		return err
+	}
+
+	_, err := mc.Conn.Write(mc.wbuf.Bytes())
+	return err
+}
+
+// Recv reads the next message from the conn and decodes it into the
+// value msg points to. It returns io.EOF once the peer closed at a
+// message boundary.
+func (mc *MessageConn) Recv(msg interface{}) error {
+	mc.rmu.Lock()
+	defer mc.rmu.Unlock()
+
+	return mc.codec.Decode(mc.Conn, msg)
+}
@@ -0,0 +1,82 @@
+package memnet
+
+import (
+	"io"
+	"testing"
+)
+
+func TestMessageConnJSON(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	type msg struct {
+		Op   string
+		Args []int
+	}
+
+	// The frames are bigger than the ring, so they arrive in pieces
+	sent := []msg{{"add", []int{1, 2, 3}}, {"neg", []int{-7}}, {"nop", nil}}
+
+	client := NewMessageConn(local, JSONCodec)
+	go func() {
+		for _, m := range sent {
+			client.Send(m)
+		}
+		client.Close()
+	}()
+
+	server := NewMessageConn(remote, JSONCodec)
+	for i, want := range sent {
+		var got msg
+		if err := server.Recv(&got); err != nil {
+			t.Fatalf("server.Recv #%d = %v", i, err)
+		}
+
+		if got.Op != want.Op || len(got.Args) != len(want.Args) {
+			t.Fatalf("server.Recv #%d = %+v, want %+v", i, got, want)
+		}
+		for j := range got.Args {
+			if got.Args[j] != want.Args[j] {
+				t.Fatalf("server.Recv #%d = %+v, want %+v", i, got, want)
+			}
+		}
+	}
+
+	if err := server.Recv(new(msg)); err != io.EOF {
+		t.Fatalf("server.Recv after close = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestMessageConnLengthPrefix(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	client := NewMessageConn(local, LengthPrefixCodec)
+	go func() {
+		client.Send([]byte("frame"))
+		client.Send([]byte{})
+		local.Write([]byte{0, 0, 0, 9, 'c', 'u', 't'})
+		client.Close()
+	}()
+
+	server := NewMessageConn(remote, LengthPrefixCodec)
+	for _, want := range []string{"frame", ""} {
+		var got []byte
+		if err := server.Recv(&got); err != nil || string(got) != want {
+			t.Fatalf("server.Recv = %q, %v, want %q, nil", got, err, want)
+		}
+	}
+
+	var got []byte
+	if err := server.Recv(&got); err != io.ErrUnexpectedEOF {
+		t.Fatalf("server.Recv of a cut frame = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	if err := client.Send("not bytes"); err != errMessageType {
+		t.Fatalf("client.Send(string) = %v, want %v", err, errMessageType)
+	}
+}
@@ -0,0 +1,138 @@
+package memnet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+)
+
+// WithTransparentCompression gzips the bytes of every Write on the way
+// into the buffer and gunzips them on the way out, in both directions.
+// Reads and writes see the original bytes, while Stats counts the
+// compressed ones. Each Write travels as one length prefixed frame, so a
+// Write bigger than the buffer which fails midway leaves the stream
+// unreadable. High priority writes are sent as normal ones.
+func WithTransparentCompression() DialOption {
+	return func(o *dialOptions) { o.compress = true }
+}
+
+// frameHeader is the size of the length prefix of a compressed frame.
+const frameHeader = 4
+
+// compressor holds the compression state of one end of a conn.
+type compressor struct {
+	// held is len(frame) + len(plain), which Buffered reads without
+	// rmu since rmu is held across blocking reads. It is accessed
+	// atomically and kept first for alignment
+	held int64
+
+	wmu  sync.Mutex
+	zw   *gzip.Writer
+	wbuf bytes.Buffer
+
+	rmu   sync.Mutex
+	frame []byte // raw frame read so far
+	plain []byte // uncompressed bytes not returned yet
+}
+
+func newCompressor() *compressor {
+	gz := &compressor{}
+	gz.zw = gzip.NewWriter(&gz.wbuf)
+	return gz
+}
+
+func (gz *compressor) write(ctx context.Context, c *conn, p []byte) (int, error) {
+	gz.wmu.Lock()
+	defer gz.wmu.Unlock()
+
+	gz.wbuf.Reset()
+	gz.wbuf.Write(make([]byte, frameHeader))
+	gz.zw.Reset(&gz.wbuf)
+	gz.zw.Write(p)
+	gz.zw.Close()
+
+	frame := gz.wbuf.Bytes()
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-frameHeader))
+
+	n, err := c.w.(*ringBuff).writeCtx(ctx, frame)
+	atomic.AddInt64(&c.nwritten, int64(n))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (gz *compressor) read(ctx context.Context, c *conn, p []byte) (int, error) {
+	gz.rmu.Lock()
+	defer gz.rmu.Unlock()
+
+	for len(gz.plain) == 0 {
+		if err := gz.readFrame(ctx, c); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, gz.plain)
+	gz.plain = gz.plain[n:]
+	gz.count()
+	return n, nil
+}
+
+// readFrame reads and uncompresses the next frame. A read which fails,
+// on a deadline for instance, keeps the part of the frame read so far.
+func (gz *compressor) readFrame(ctx context.Context, c *conn) error {
+	want := frameHeader
+	if len(gz.frame) >= frameHeader {
+		want += int(binary.BigEndian.Uint32(gz.frame))
+	}
+
+	for len(gz.frame) < want {
+		if cap(gz.frame) < want {
+			frame := make([]byte, len(gz.frame), want)
+			copy(frame, gz.frame)
+			gz.frame = frame
+		}
+
+		n, err := c.r.(*ringBuff).readCtx(ctx, gz.frame[len(gz.frame):want])
+		gz.frame = gz.frame[:len(gz.frame)+n]
+		gz.count()
+		atomic.AddInt64(&c.nread, int64(n))
+
+		if err == io.EOF && len(gz.frame) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(gz.frame) == frameHeader {
+			want += int(binary.BigEndian.Uint32(gz.frame))
+		}
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(gz.frame[frameHeader:]))
+	if err != nil {
+		return err
+	}
+
+	gz.plain, err = ioutil.ReadAll(zr)
+	gz.frame = gz.frame[:0]
+	gz.count()
+	return err
+}
+
+// count updates held, gz.rmu must be held.
+func (gz *compressor) count() {
+	atomic.StoreInt64(&gz.held, int64(len(gz.frame)+len(gz.plain)))
+}
+
+// buffered returns the bytes read out of the ring which the conn's
+// reader didn't get yet, compressed or not.
+func (gz *compressor) buffered() int {
+	return int(atomic.LoadInt64(&gz.held))
+}
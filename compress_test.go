@@ -0,0 +1,90 @@
+package memnet
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func compressServe(t *testing.T) (*conn, *conn) {
+	ln, err := Listen(dLnOptn.c, 1024, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	local, err := ln.DialContext(context.Background(), WithTransparentCompression())
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	return local.(*conn), remote.(*conn)
+}
+
+func TestTransparentCompression(t *testing.T) {
+	local, remote := compressServe(t)
+
+	input := bytes.Repeat([]byte("compressible "), 100)
+	output := make([]byte, 2*len(input))
+	readCh := doRead(remote, output)
+
+	for i := 0; i < 2; i++ {
+		if n, err := local.Write(input); n != len(input) || err != nil {
+			t.Fatalf("local.Write = %d, %v, want %d, nil", n, err, len(input))
+		}
+	}
+
+	if result := <-readCh; result.err != nil {
+		t.Fatalf(errReadRemoteConn, result.err.Error())
+	}
+
+	if !bytes.Equal(output, append(input, input...)) {
+		t.Fatalf("remote read %d bytes which differ from the input", len(output))
+	}
+
+	wire := local.Stats().BytesWritten
+	if wire >= int64(len(input)) {
+		t.Fatalf("Stats().BytesWritten = %d, want < %d", wire, len(input))
+	}
+
+	if got := remote.Stats().BytesRead; got != wire {
+		t.Fatalf("remote Stats().BytesRead = %d, want %d", got, wire)
+	}
+
+	local.Close()
+	if _, err := remote.Read(output); err != io.EOF {
+		t.Fatalf("remote.Read = _, %v, want %v", err, io.EOF)
+	}
+}
+
+func TestTransparentCompressionDeadline(t *testing.T) {
+	local, remote := compressServe(t)
+
+	remote.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	if _, err := remote.Read(make([]byte, 1)); err != errTimeout {
+		t.Fatalf("remote.Read = _, %v, want %v", err, errTimeout)
+	}
+
+	// A timed out read doesn't break the stream
+	remote.SetReadDeadline(time.Time{})
+
+	input := []byte("still readable")
+	if _, err := local.Write(input); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	output := make([]byte, len(input))
+	if _, err := io.ReadFull(remote, output); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	if !bytes.Equal(input, output) {
+		t.Fatalf(errIOMismatched, input, output)
+	}
+}
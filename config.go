@@ -0,0 +1,75 @@
+package memnet
+
+import (
+	"fmt"
+	"net"
+)
+
+// Config holds the listener defaults which Reconfigure can change, they
+// are the arguments of Listen.
+type Config struct {
+	// ConnQueueSize is the number of dialed conns which can wait to be
+	// accepted till the dials block
+	ConnQueueSize int
+
+	// BufferSize is the transport buffer size of new conns, zero
+	// meaning unbuffered conns
+	BufferSize int
+}
+
+// Reconfigure swaps the listener's defaults for cfg. Conns dialed from
+// then on get the new buffer size and queue up in an accept queue of the
+// new size, while the open conns and the ones already queued are left
+// as they are. Accept queues of labels which were dialed already keep
+// their size. An invalid cfg is rejected and changes nothing.
+func (l *Listener) Reconfigure(cfg Config) error {
+	if cfg.ConnQueueSize < 0 || cfg.BufferSize < 0 {
+		return fmt.Errorf("invalid config")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.bsz = cfg.BufferSize
+
+	if cfg.ConnQueueSize == cap(l.connCh) {
+		return nil
+	}
+
+	old := l.connCh
+	l.connCh = make(chan net.Conn, cfg.ConnQueueSize)
+
+	// The queued conns move over first to keep their place, whatever
+	// doesn't fit or is still being dialed follows later
+move:
+	for len(l.connCh) < cap(l.connCh) {
+		select {
+		case c := <-old:
+			l.connCh <- c
+		default:
+			break move
+		}
+	}
+	go l.forward(old, l.connCh)
+
+	close(l.rewire)
+	l.rewire = make(chan struct{})
+	return nil
+}
+
+// forward moves the conns queued on a replaced accept queue, and those
+// of the dials still blocked on it, over to its successor.
+func (l *Listener) forward(from, to chan net.Conn) {
+	for {
+		select {
+		case c := <-from:
+			select {
+			case to <- c:
+			case <-l.done:
+				return
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
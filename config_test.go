@@ -0,0 +1,97 @@
+package memnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestListenerReconfigure(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	before, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	if err := ln.Reconfigure(Config{ConnQueueSize: -1}); err == nil {
+		t.Fatal("ln.Reconfigure accepted a negative queue size")
+	}
+
+	if err := ln.Reconfigure(Config{ConnQueueSize: 3, BufferSize: 32}); err != nil {
+		t.Fatalf("ln.Reconfigure = %v", err)
+	}
+
+	// Two more dials fit in the bigger queue without an Accept
+	var after []*conn
+	for i := 0; i < 2; i++ {
+		c, err := ln.DialTimeout(time.Second)
+		if err != nil {
+			t.Fatalf(errMemServer, err.Error())
+		}
+		after = append(after, c.(*conn))
+	}
+
+	if n := before.(*conn).Cap(); n != dLnOptn.t {
+		t.Fatalf("Cap() of the conn dialed before = %d, want %d", n, dLnOptn.t)
+	}
+
+	for _, c := range after {
+		if n := c.Cap(); n != 32 {
+			t.Fatalf("Cap() of a conn dialed after = %d, want %d", n, 32)
+		}
+	}
+
+	// The conn queued before comes out first
+	for i := 0; i < 3; i++ {
+		remote, err := ln.Accept()
+		if err != nil {
+			t.Fatalf(errAcceptMemConn, err.Error())
+		}
+
+		want := 32
+		if i == 0 {
+			want = dLnOptn.t
+		}
+
+		if n := remote.(*conn).Cap(); n != want {
+			t.Fatalf("Cap() of accept %d = %d, want %d", i, n, want)
+		}
+	}
+}
+
+func TestListenerReconfigureBlockedAccept(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	accepted := make(chan error, 1)
+	go func() {
+		_, err := ln.Accept()
+		accepted <- err
+	}()
+
+	for !ln.HasPendingAccept() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := ln.Reconfigure(Config{ConnQueueSize: 2, BufferSize: dLnOptn.t}); err != nil {
+		t.Fatalf("ln.Reconfigure = %v", err)
+	}
+
+	if _, err := ln.Dial(); err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	select {
+	case err := <-accepted:
+		if err != nil {
+			t.Fatalf(errAcceptMemConn, err.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Accept blocked before Reconfigure missed the dial")
+	}
+}
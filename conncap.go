@@ -0,0 +1,38 @@
+package memnet
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+)
+
+// errTooManyConns is what a dial fails with once the listener has as
+// many open conns as WithMaxConns allows.
+var errTooManyConns net.Error = netErrTemporary{fmt.Errorf("too many connections")}
+
+// WithMaxConns caps the number of open conns of the listener, as counted
+// by NumConns, to n, which models the connection limit of a service. It
+// is meant for the listeners registered on a Fabric with ListenNamed: a
+// dial to the name fails with a temporary net.Error while its listener
+// is at the cap, and goes through again once one of the conns closed. A
+// n <= 0 means no cap.
+func WithMaxConns(n int) Option {
+	return func(l *Listener) {
+		l.maxConns = int64(n)
+	}
+}
+
+// addConn counts a new conn of the listener, it reports false without
+// counting it if the listener is at the cap of WithMaxConns.
+func (l *Listener) addConn() bool {
+	for {
+		n := atomic.LoadInt64(&l.nconns)
+		if l.maxConns > 0 && n >= l.maxConns {
+			return false
+		}
+
+		if atomic.CompareAndSwapInt64(&l.nconns, n, n+1) {
+			return true
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package memnet
+
+import (
+	"net"
+	"testing"
+)
+
+func TestFabricMaxConns(t *testing.T) {
+	const max = 2
+
+	f := NewFabric(dLnOptn.c, dLnOptn.t)
+
+	client, err := f.Listen("client")
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer client.Close()
+
+	ln, err := f.ListenNamed("svc", false, WithMaxConns(max))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	var conns []net.Conn
+	for i := 0; i < max; i++ {
+		local, err := f.Dial("client", "svc")
+		if err != nil {
+			t.Fatalf(errMemServer, err.Error())
+		}
+
+		if _, err := ln.Accept(); err != nil {
+			t.Fatalf(errAcceptMemConn, err.Error())
+		}
+		conns = append(conns, local)
+	}
+
+	_, err = f.Dial("client", "svc")
+	if err != errTooManyConns {
+		t.Fatalf("f.Dial past the cap = _, %v, want %v", err, errTooManyConns)
+	}
+
+	if nerr, ok := err.(net.Error); !ok || !nerr.Temporary() {
+		t.Fatalf("f.Dial past the cap = _, %v, want a temporary net.Error", err)
+	}
+
+	if n := ln.NumConns(); n != max {
+		t.Fatalf("ln.NumConns() = %d, want %d", n, max)
+	}
+
+	// A conn which closes makes room for the next dial
+	conns[0].Close()
+	if _, err := f.Dial("client", "svc"); err != nil {
+		t.Fatalf("f.Dial after a Close = _, %v, want nil", err)
+	}
+}
@@ -0,0 +1,90 @@
+package memnet
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+)
+
+// ConnQueue is a bounded queue of conns for handing accepted conns to a
+// pool of workers. It is safe for concurrent use.
+type ConnQueue struct {
+	ch        chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewConnQueue returns an empty ConnQueue which holds up to size conns.
+func NewConnQueue(size int) *ConnQueue {
+	return &ConnQueue{
+		ch:   make(chan net.Conn, size),
+		done: make(chan struct{}),
+	}
+}
+
+// Push queues c, blocking while the queue is full. It fails with
+// io.ErrClosedPipe once the queue is closed.
+func (q *ConnQueue) Push(c net.Conn) error {
+	return q.PushContext(context.Background(), c)
+}
+
+// PushContext is Push which also gives up once ctx is done.
+func (q *ConnQueue) PushContext(ctx context.Context, c net.Conn) error {
+	select {
+	case <-q.done:
+		return io.ErrClosedPipe
+	default:
+	}
+
+	select {
+	case q.ch <- c:
+		return nil
+	case <-q.done:
+		return io.ErrClosedPipe
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pop takes the oldest conn off the queue, blocking while it is empty.
+// The conns queued before Close are still returned, after them Pop
+// fails with io.ErrClosedPipe.
+func (q *ConnQueue) Pop() (net.Conn, error) {
+	return q.PopContext(context.Background())
+}
+
+// PopContext is Pop which also gives up once ctx is done.
+func (q *ConnQueue) PopContext(ctx context.Context) (net.Conn, error) {
+	select {
+	case c := <-q.ch:
+		return c, nil
+	case <-q.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	// Closed, but conns may still be queued
+	select {
+	case c := <-q.ch:
+		return c, nil
+	default:
+		return nil, io.ErrClosedPipe
+	}
+}
+
+// Len returns the number of queued conns.
+func (q *ConnQueue) Len() int {
+	return len(q.ch)
+}
+
+// Close makes Push fail and Pop fail once the queue is drained. The
+// queued conns are left open.
+func (q *ConnQueue) Close() error {
+	err := io.ErrClosedPipe
+	q.closeOnce.Do(func() {
+		close(q.done)
+		err = nil
+	})
+	return err
+}
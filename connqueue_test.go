@@ -0,0 +1,126 @@
+package memnet
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConnQueue(t *testing.T) {
+	ln, err := Listen(8, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	const producers, perProducer, workers = 4, 8, 3
+	q := NewConnQueue(2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < producers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perProducer; j++ {
+				local, err := ln.Dial()
+				if err != nil {
+					t.Errorf(errMemServer, err.Error())
+					return
+				}
+
+				remote, err := ln.Accept()
+				if err != nil {
+					t.Errorf(errAcceptMemConn, err.Error())
+					return
+				}
+
+				local.Close()
+				if err := q.Push(remote); err != nil {
+					t.Errorf("q.Push = %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	popped := make(chan net.Conn, producers*perProducer)
+	var workersWg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		workersWg.Add(1)
+		go func() {
+			defer workersWg.Done()
+			for {
+				c, err := q.Pop()
+				if err == io.ErrClosedPipe {
+					return
+				}
+				if err != nil {
+					t.Errorf("q.Pop = _, %v", err)
+					return
+				}
+				popped <- c
+			}
+		}()
+	}
+
+	wg.Wait()
+	q.Close()
+	workersWg.Wait()
+	close(popped)
+
+	seen := make(map[net.Conn]bool)
+	for c := range popped {
+		if seen[c] {
+			t.Fatalf("conn popped twice")
+		}
+		seen[c] = true
+	}
+
+	if len(seen) != producers*perProducer {
+		t.Fatalf("popped %d conns, want %d", len(seen), producers*perProducer)
+	}
+
+	if err := q.Push(nil); err != io.ErrClosedPipe {
+		t.Fatalf("q.Push after Close = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestConnQueueContext(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	q := NewConnQueue(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := q.PopContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("q.PopContext of an empty queue = _, %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if err := q.PushContext(context.Background(), local); err != nil {
+		t.Fatalf("q.PushContext = %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := q.PushContext(ctx, remote); err != context.DeadlineExceeded {
+		t.Fatalf("q.PushContext to a full queue = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Closing keeps the queued conn for Pop
+	q.Close()
+	if c, err := q.Pop(); c != local || err != nil {
+		t.Fatalf("q.Pop after Close = %v, %v, want the queued conn", c, err)
+	}
+
+	if _, err := q.Pop(); err != io.ErrClosedPipe {
+		t.Fatalf("q.Pop of a closed, drained queue = _, %v, want %v", err, io.ErrClosedPipe)
+	}
+}
@@ -0,0 +1,95 @@
+package memnet
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// copyN writes the next n bytes to w, waiting for them like readCtx,
+// and returns how many it wrote. The bytes are taken out of the ring a
+// chunk at a time and written to w with the ring unlocked, so a w which
+// blocks holds up neither the deadlines nor Close nor the writers.
+func (rb *ringBuff) copyN(ctx context.Context, w io.Writer, n int64) (int64, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+
+	defer rb.watch(ctx, &rb.rdwait)()
+
+	size := int64(copyBufSize)
+	if n < size {
+		size = n
+	}
+	buf := make([]byte, size)
+
+	var copied int64
+	for copied < n {
+		p := buf
+		if left := n - copied; int64(len(p)) > left {
+			p = p[:left]
+		}
+
+		rb.mu.Lock()
+		err := rb.awaitReadable(ctx)
+		if err == nil {
+			p = p[:rb.take(p)]
+		}
+		rb.mu.Unlock()
+
+		if err != nil {
+			return copied, err
+		}
+
+		wn, err := w.Write(p)
+		copied += int64(wn)
+
+		if err == nil && wn < len(p) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return copied, err
+		}
+	}
+	return copied, nil
+}
+
+// CopyN writes exactly the next n bytes the peer sends to w, like
+// io.CopyN but without going through Read, and leaves the rest
+// buffered. It blocks till n bytes went by and fails like Read on
+// deadlines and close. It fails with io.ErrUnexpectedEOF if the peer
+// closes early, and returns the number of bytes copied in any case.
+// The bytes it took out of the buffer are gone even if w fails them.
+func (c *conn) CopyN(w io.Writer, n int64) (int64, error) {
+	copied, ok, err := c.copyDirect(w, n)
+	if !ok {
+		copied, err = io.CopyN(w, c, n)
+	}
+
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return copied, err
+}
+
+// copyDirect is copyN on the ring the conn reads from, which reports
+// false without copying anything when the bytes have to be seen to be
+// decoded, teed or recorded.
+func (c *conn) copyDirect(w io.Writer, n int64) (int64, bool, error) {
+	c.mu.Lock()
+	teed := len(c.tees) > 0
+	c.mu.Unlock()
+
+	if c.gz != nil || teed || c.pair.transcript != nil {
+		return 0, false, nil
+	}
+
+	if c.sched != nil {
+		c.sched.acquire(c.id)
+		defer c.sched.release(c.id)
+	}
+
+	copied, err := c.r.(*ringBuff).copyN(context.Background(), w, n)
+	atomic.AddInt64(&c.nread, copied)
+	return copied, true, err
+}
@@ -0,0 +1,167 @@
+package memnet
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestConnCopyN(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []DialOption
+	}{
+		{"plain", nil},
+		{"compressed", []DialOption{WithTransparentCompression()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+			if err != nil {
+				t.Fatalf(errMemListener, err.Error())
+			}
+
+			local, err := ln.DialContext(context.Background(), tc.opts...)
+			if err != nil {
+				t.Fatalf(errMemServer, err.Error())
+			}
+
+			remote, err := ln.Accept()
+			if err != nil {
+				t.Fatalf(errAcceptMemConn, err.Error())
+			}
+
+			// 19 bytes don't fit in the ring at once
+			input := []byte("body-of-12b|trailer")
+			writeCh := doWrite(local, input)
+
+			var body bytes.Buffer
+			if n, err := remote.(*conn).CopyN(&body, 12); n != 12 || err != nil {
+				t.Fatalf("CopyN = %d, %v, want %d, nil", n, err, 12)
+			}
+
+			if body.String() != "body-of-12b|" {
+				t.Fatalf(errIOMismatched, "body-of-12b|", body.String())
+			}
+
+			<-writeCh
+			local.Close()
+
+			// The rest is left for Read, and CopyN asking for more than
+			// the peer sent fails
+			var rest bytes.Buffer
+			n, err := remote.(*conn).CopyN(&rest, 100)
+			if n != int64(len("trailer")) || err != io.ErrUnexpectedEOF {
+				t.Fatalf("CopyN = %d, %v, want %d, %v", n, err, len("trailer"), io.ErrUnexpectedEOF)
+			}
+
+			if rest.String() != "trailer" {
+				t.Fatalf(errIOMismatched, "trailer", rest.String())
+			}
+		})
+	}
+}
+
+func TestConnCopyNNothing(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := local.Write([]byte("abc")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	// Like io.CopyN, a count of zero or less copies nothing
+	for _, n := range []int64{0, -1} {
+		var out bytes.Buffer
+		if copied, err := remote.(*conn).CopyN(&out, n); copied != 0 || err != nil || out.Len() != 0 {
+			t.Fatalf("CopyN(%d) = %d, %v, want 0, nil", n, copied, err)
+		}
+	}
+
+	if n := remote.(*conn).Buffered(); n != 3 {
+		t.Fatalf("Buffered() = %d, want 3", n)
+	}
+}
+
+func TestConnCopyNBlockedWriter(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rc := remote.(*conn)
+
+	if _, err := local.Write([]byte("abcd")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	// Nobody reads pr yet, so the write to pw blocks
+	pr, pw := io.Pipe()
+	done := make(chan ioResult, 1)
+	go func() {
+		n, err := rc.CopyN(pw, 8)
+		done <- ioResult{int(n), err}
+	}()
+
+	// The conn stays usable while w blocks
+	returns := func(name string, f func()) {
+		t.Helper()
+		ch := make(chan struct{})
+		go func() {
+			f()
+			close(ch)
+		}()
+
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatalf("%s blocked on the write to w", name)
+		}
+	}
+
+	// CopyN is parked on w once it took the bytes out of the buffer
+	taken := func() {
+		t.Helper()
+		returns("Buffered", func() {
+			for rc.Buffered() != 0 {
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+
+	taken()
+
+	returns("SetReadDeadline", func() { rc.SetReadDeadline(time.Now()) })
+
+	// Once w takes the bytes the deadline stops the wait for the rest
+	output := make([]byte, 4)
+	if _, err := io.ReadFull(pr, output); err != nil || string(output) != "abcd" {
+		t.Fatalf("io.ReadFull(pr) = %q, %v, want %q, nil", output, err, "abcd")
+	}
+
+	if result := <-done; result.n != 4 || result.err != errTimeout {
+		t.Fatalf("CopyN = %d, %v, want 4, %v", result.n, result.err, errTimeout)
+	}
+
+	// A CopyN blocked on w doesn't hold up Close either
+	rc.SetReadDeadline(time.Time{})
+	if _, err := local.Write([]byte("efgh")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	go func() {
+		n, err := rc.CopyN(pw, 8)
+		done <- ioResult{int(n), err}
+	}()
+
+	taken()
+
+	returns("Close", func() { remote.Close() })
+
+	pr.Close()
+	if result := <-done; result.n != 0 || result.err != io.ErrClosedPipe {
+		t.Fatalf("CopyN = %d, %v, want 0, %v", result.n, result.err, io.ErrClosedPipe)
+	}
+}
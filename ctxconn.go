@@ -0,0 +1,64 @@
+package memnet
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// NewContextConn ties the I/O of c to ctx: once ctx is done, c is closed
+// and the pending and future Reads and Writes fail with ctx.Err(). Close
+// closes c and stops watching ctx. It works with conns of any kind.
+func NewContextConn(ctx context.Context, c net.Conn) net.Conn {
+	cc := &ctxConn{Conn: c, ctx: ctx, done: make(chan struct{})}
+	go cc.watch()
+	return cc
+}
+
+type ctxConn struct {
+	net.Conn
+	ctx       context.Context
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// watch closes the conn once ctx is done.
+func (cc *ctxConn) watch() {
+	select {
+	case <-cc.ctx.Done():
+		cc.Conn.Close()
+	case <-cc.done:
+	}
+}
+
+// ctxErr replaces err with the error of ctx once it is done, the error
+// of the closed conn would hide why it got closed.
+func (cc *ctxConn) ctxErr(err error) error {
+	if cerr := cc.ctx.Err(); err != nil && cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+func (cc *ctxConn) Read(p []byte) (int, error) {
+	if err := cc.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := cc.Conn.Read(p)
+	return n, cc.ctxErr(err)
+}
+
+func (cc *ctxConn) Write(p []byte) (int, error) {
+	if err := cc.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := cc.Conn.Write(p)
+	return n, cc.ctxErr(err)
+}
+
+func (cc *ctxConn) Close() error {
+	cc.closeOnce.Do(func() { close(cc.done) })
+	return cc.Conn.Close()
+}
@@ -0,0 +1,52 @@
+package memnet
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestContextConn(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cc := NewContextConn(ctx, local)
+
+	readCh := make(chan error, 1)
+	go func() {
+		_, err := cc.Read(make([]byte, 1))
+		readCh <- err
+	}()
+
+	select {
+	case err := <-readCh:
+		t.Fatalf("cc.Read = _, %v, want it to block", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel()
+
+	select {
+	case err := <-readCh:
+		if err != context.Canceled {
+			t.Fatalf("cc.Read = _, %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("cc.Read still blocks after the cancel")
+	}
+
+	if _, err := cc.Write([]byte("hi")); err != context.Canceled {
+		t.Fatalf("cc.Write = _, %v, want %v", err, context.Canceled)
+	}
+
+	// The cancel closed the conn
+	if _, err := remote.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("remote.Read = _, %v, want %v", err, io.EOF)
+	}
+}
@@ -0,0 +1,68 @@
+package memnet
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// discard skips over n bytes, waiting for them like readCtx, and returns
+// how many it skipped.
+func (rb *ringBuff) discard(ctx context.Context, n int) (int, error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	defer rb.watch(ctx, &rb.rdwait)()
+
+	var skipped int
+	for skipped < n {
+		if err := rb.awaitReadable(ctx); err != nil {
+			return skipped, err
+		}
+
+		skipped += rb.consume(nil, n-skipped)
+	}
+	return skipped, nil
+}
+
+// DiscardN skips over the next n bytes the peer sends without copying
+// them out, like bufio.Reader.Discard. It blocks till n bytes went by
+// and fails like Read on EOF, deadlines and close, returning how many
+// bytes it skipped before the failure.
+func (c *conn) DiscardN(n int) (int, error) {
+	c.mu.Lock()
+	teed := len(c.tees) > 0
+	c.mu.Unlock()
+
+	// The bytes have to be seen to be decoded, teed or recorded
+	if c.gz != nil || teed || c.pair.transcript != nil {
+		return c.discardCopy(n)
+	}
+
+	if c.sched != nil {
+		c.sched.acquire(c.id)
+		defer c.sched.release(c.id)
+	}
+
+	skipped, err := c.r.(*ringBuff).discard(context.Background(), n)
+	atomic.AddInt64(&c.nread, int64(skipped))
+	return skipped, err
+}
+
+// discardCopy is DiscardN reading the bytes into a scratch buffer.
+func (c *conn) discardCopy(n int) (int, error) {
+	buf := make([]byte, copyBufSize)
+
+	var skipped int
+	for skipped < n {
+		p := buf
+		if left := n - skipped; left < len(p) {
+			p = p[:left]
+		}
+
+		rn, err := c.Read(p)
+		skipped += rn
+		if err != nil {
+			return skipped, err
+		}
+	}
+	return skipped, nil
+}
@@ -0,0 +1,73 @@
+package memnet
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestConnDiscardN(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []DialOption
+	}{
+		{"plain", nil},
+		{"compressed", []DialOption{WithTransparentCompression()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+			if err != nil {
+				t.Fatalf(errMemListener, err.Error())
+			}
+
+			local, err := ln.DialContext(context.Background(), tc.opts...)
+			if err != nil {
+				t.Fatalf(errMemServer, err.Error())
+			}
+
+			remote, err := ln.Accept()
+			if err != nil {
+				t.Fatalf(errAcceptMemConn, err.Error())
+			}
+
+			// 20 bytes don't fit in the ring at once
+			input := []byte("headers!0123456789ab")
+			writeCh := doWrite(local, input)
+
+			if n, err := remote.(*conn).DiscardN(8); n != 8 || err != nil {
+				t.Fatalf("DiscardN = %d, %v, want %d, nil", n, err, 8)
+			}
+
+			output := make([]byte, 12)
+			if _, err := io.ReadFull(remote, output); err != nil {
+				t.Fatalf(errReadRemoteConn, err)
+			}
+
+			if string(output) != string(input[8:]) {
+				t.Fatalf(errIOMismatched, input[8:], output)
+			}
+
+			if res := <-writeCh; res.err != nil {
+				t.Fatalf(errWriteLocalConn, res.err)
+			}
+		})
+	}
+}
+
+func TestConnDiscardNDeadline(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := local.Write([]byte("abc")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	remote.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+
+	if n, err := remote.(*conn).DiscardN(8); n != 3 || err != errTimeout {
+		t.Fatalf("DiscardN = %d, %v, want %d, %v", n, err, 3, errTimeout)
+	}
+}
@@ -0,0 +1,80 @@
+package memnet
+
+// WithHalfDuplex lets only one direction of each conn transmit at a
+// time, like a walkie-talkie. A Write takes the turn and keeps it until
+// the peer has read everything written; a Write on the other end blocks
+// until then.
+func WithHalfDuplex() Option {
+	return func(l *Listener) {
+		l.halfDuplex = true
+	}
+}
+
+// duplex is the transmit turn of a half-duplex conn. It is guarded by
+// the mutex its two rings share.
+type duplex struct {
+	rings [2]*ringBuff
+	owner *ringBuff
+}
+
+// newDuplex makes the rings of the two directions share a turn and a
+// mutex.
+func newDuplex(a, b *ringBuff) {
+	d := &duplex{rings: [2]*ringBuff{a, b}}
+
+	b.mu = a.mu
+	b.rdwait.L = a.mu
+	b.wrwait.L = a.mu
+
+	a.duplex, b.duplex = d, d
+}
+
+// mayWrite reports whether rb can be written into. A nil *duplex always
+// allows writes.
+func (d *duplex) mayWrite(rb *ringBuff) bool {
+	return d == nil || d.owner == nil || d.owner == rb
+}
+
+func (d *duplex) take(rb *ringBuff) {
+	if d != nil {
+		d.owner = rb
+	}
+}
+
+// release hands back the turn held by rb and wakes up the writers of
+// both directions.
+func (d *duplex) release(rb *ringBuff) {
+	if d == nil || d.owner != rb {
+		return
+	}
+
+	d.owner = nil
+	for _, r := range d.rings {
+		r.wrwait.Broadcast()
+	}
+}
+
+// takeTurn takes the turn for a write into rb, which keeps it till the
+// returned func is called once the write is done, even if the reader
+// drains rb in the middle of it. rb.mu must be held.
+func (rb *ringBuff) takeTurn() func() {
+	if rb.duplex == nil {
+		return func() {}
+	}
+
+	rb.duplex.take(rb)
+	rb.writing++
+
+	return func() {
+		rb.writing--
+		rb.drained()
+	}
+}
+
+// drained releases the turn once the reader consumed everything and no
+// write is in progress. rb.mu must be held.
+func (rb *ringBuff) drained() {
+	if rb.duplex != nil && rb.writing == 0 && rb.unread() == 0 {
+		rb.duplex.release(rb)
+	}
+}
@@ -0,0 +1,118 @@
+package memnet
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHalfDuplex(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, WithHalfDuplex())
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	over := []byte("over")
+	if _, err := local.Write(over); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	writeCh := doWrite(remote, []byte("roger"))
+
+	select {
+	case result := <-writeCh:
+		t.Fatalf("remote.Write = %d, %v while local holds the turn", result.n, result.err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	output := make([]byte, len(over))
+	if _, err := io.ReadFull(remote, output); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	if !reflect.DeepEqual(over, output) {
+		t.Fatalf(errIOMismatched, over, output)
+	}
+
+	if result := <-writeCh; result.n != 5 || result.err != nil {
+		t.Fatalf("remote.Write = %d, %v, want %d, nil", result.n, result.err, 5)
+	}
+
+	// Now local has to wait for its turn
+	writeCh = doWrite(local, []byte("out"))
+
+	select {
+	case result := <-writeCh:
+		t.Fatalf("local.Write = %d, %v while remote holds the turn", result.n, result.err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if _, err := io.ReadFull(local, make([]byte, 5)); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	if result := <-writeCh; result.err != nil {
+		t.Fatalf("local.Write = %d, %v, want %d, nil", result.n, result.err, 3)
+	}
+}
+
+func TestHalfDuplexWriteBiggerThanBuffer(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, WithHalfDuplex())
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	input := make([]byte, 2*dLnOptn.t+5)
+	localCh := doWrite(local, input)
+
+	// Draining the first part doesn't hand over the turn, the write
+	// isn't done yet
+	if _, err := io.ReadFull(remote, make([]byte, dLnOptn.t)); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	remoteCh := doWrite(remote, []byte("x"))
+
+	select {
+	case result := <-remoteCh:
+		t.Fatalf("remote.Write = %d, %v in the middle of local's write", result.n, result.err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if _, err := io.ReadFull(remote, make([]byte, len(input)-dLnOptn.t)); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	if result := <-localCh; result.n != len(input) || result.err != nil {
+		t.Fatalf("local.Write = %d, %v, want %d, nil", result.n, result.err, len(input))
+	}
+
+	if result := <-remoteCh; result.n != 1 || result.err != nil {
+		t.Fatalf("remote.Write = %d, %v, want 1, nil", result.n, result.err)
+	}
+
+	if _, err := io.ReadFull(local, make([]byte, 1)); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+}
@@ -0,0 +1,121 @@
+package memnet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+)
+
+var errUnknownEndpoint = fmt.Errorf("unknown endpoint")
+
+// Fabric is an in-memory switch which routes stream connections between
+// the endpoints registered on it by name.
+type Fabric struct {
+	mu        sync.Mutex
+	connQSize int
+	bsz       int
+	nodes     map[string]*endpoint
+}
+
+// endpoint is the set of listeners registered under one name. There is
+// more than one only if they were all registered with reuse.
+type endpoint struct {
+	lns   []*Listener
+	reuse bool
+
+	// next is the round-robin position of the next dial
+	next int
+}
+
+// live returns the listeners of the endpoint which are still open.
+func (e *endpoint) live() []*Listener {
+	var lns []*Listener
+	for _, l := range e.lns {
+		if !l.isClosed() {
+			lns = append(lns, l)
+		}
+	}
+	return lns
+}
+
+// pick returns the listener the next dial to the endpoint goes to. With
+// all of them closed it returns one which fails the dial.
+func (e *endpoint) pick() *Listener {
+	lns := e.live()
+	if len(lns) == 0 {
+		return e.lns[len(e.lns)-1]
+	}
+
+	l := lns[e.next%len(lns)]
+	e.next++
+	return l
+}
+
+// NewFabric returns an empty fabric whose endpoints can queue connQSize
+// number of new connections and have transport buffer size of
+// transBuffSize.
+func NewFabric(connQSize, transBuffSize int) *Fabric {
+	return &Fabric{
+		connQSize: connQSize,
+		bsz:       transBuffSize,
+		nodes:     make(map[string]*endpoint),
+	}
+}
+
+// Listen registers the endpoint name and returns the listener which
+// accepts the connections dialed to it.
+func (f *Fabric) Listen(name string, opts ...Option) (*Listener, error) {
+	return f.ListenNamed(name, false, opts...)
+}
+
+// ListenNamed is like Listen, but with reuse set several listeners can
+// share the endpoint name, like sockets bound with SO_REUSEPORT: dials to
+// the name are spread round-robin across the open ones. All of the
+// listeners sharing a name must be registered with reuse.
+func (f *Fabric) ListenNamed(name string, reuse bool, opts ...Option) (*Listener, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ep, ok := f.nodes[name]
+	if ok {
+		ep.lns = ep.live()
+	}
+
+	if !ok || len(ep.lns) == 0 {
+		ep = &endpoint{reuse: reuse}
+	} else if !reuse || !ep.reuse {
+		return nil, errAddrInUse
+	}
+
+	l, err := Listen(f.connQSize, f.bsz, name, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ep.lns = append(ep.lns, l)
+	f.nodes[name] = ep
+	return l, nil
+}
+
+// Dial connects endpoint from to endpoint to. The returned conn reports
+// from as its LocalAddr, and its peer is accepted on the listener of to
+// with from as its RemoteAddr.
+func (f *Fabric) Dial(from, to string) (net.Conn, error) {
+	return f.DialContext(context.Background(), from, to)
+}
+
+// DialContext is like Dial but gives up once ctx is done.
+func (f *Fabric) DialContext(ctx context.Context, from, to string, opts ...DialOption) (net.Conn, error) {
+	f.mu.Lock()
+	src, dst := f.nodes[from], f.nodes[to]
+	if src == nil || dst == nil {
+		f.mu.Unlock()
+		return nil, errUnknownEndpoint
+	}
+	laddr, l := src.lns[0].Addr(), dst.pick()
+	f.mu.Unlock()
+
+	opts = append(opts[:len(opts):len(opts)], func(o *dialOptions) { o.laddr = laddr })
+	return l.DialContext(ctx, opts...)
+}
@@ -0,0 +1,119 @@
+package memnet
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestFabricMesh(t *testing.T) {
+	f := NewFabric(dLnOptn.c, dLnOptn.t)
+
+	nodes := []string{"a", "b", "c"}
+	lns := make(map[string]*Listener)
+	for _, name := range nodes {
+		ln, err := f.Listen(name)
+		if err != nil {
+			t.Fatalf(errMemListener, err.Error())
+		}
+		defer ln.Close()
+		lns[name] = ln
+	}
+
+	if _, err := f.Listen("a"); err != errAddrInUse {
+		t.Fatalf("f.Listen(a) = _, %v, want %v", err, errAddrInUse)
+	}
+
+	if _, err := f.Dial("a", "z"); err != errUnknownEndpoint {
+		t.Fatalf("f.Dial(a, z) = _, %v, want %v", err, errUnknownEndpoint)
+	}
+
+	for _, from := range nodes {
+		for _, to := range nodes {
+			if from == to {
+				continue
+			}
+
+			local, err := f.Dial(from, to)
+			if err != nil {
+				t.Fatalf(errMemServer, err.Error())
+			}
+
+			remote, err := lns[to].Accept()
+			if err != nil {
+				t.Fatalf(errAcceptMemConn, err.Error())
+			}
+
+			if local.LocalAddr().String() != from || local.RemoteAddr().String() != to {
+				t.Fatalf("local addrs = %v -> %v, want %v -> %v",
+					local.LocalAddr(), local.RemoteAddr(), from, to)
+			}
+
+			if remote.RemoteAddr().String() != from {
+				t.Fatalf("remote.RemoteAddr() = %v, want %v", remote.RemoteAddr(), from)
+			}
+
+			input := []byte(fmt.Sprintf("%s->%s", from, to))
+			if _, err := local.Write(input); err != nil {
+				t.Fatalf(errWriteLocalConn, err.Error())
+			}
+
+			output := make([]byte, len(input))
+			if _, err := io.ReadFull(remote, output); err != nil {
+				t.Fatalf(errReadRemoteConn, err.Error())
+			}
+
+			if string(input) != string(output) {
+				t.Fatalf(errIOMismatched, input, output)
+			}
+		}
+	}
+}
+
+func TestFabricReuse(t *testing.T) {
+	f := NewFabric(8, dLnOptn.t)
+
+	if _, err := f.Listen("client"); err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	lns := make([]*Listener, 2)
+	for i := range lns {
+		ln, err := f.ListenNamed("svc", true)
+		if err != nil {
+			t.Fatalf(errMemListener, err.Error())
+		}
+		lns[i] = ln
+	}
+
+	if _, err := f.Listen("svc"); err != errAddrInUse {
+		t.Fatalf("f.Listen(svc) = _, %v, want %v", err, errAddrInUse)
+	}
+
+	if _, err := f.ListenNamed("client", true); err != errAddrInUse {
+		t.Fatalf("f.ListenNamed(client) = _, %v, want %v", err, errAddrInUse)
+	}
+
+	const dials = 6
+	for i := 0; i < dials; i++ {
+		if _, err := f.Dial("client", "svc"); err != nil {
+			t.Fatalf(errMemServer, err.Error())
+		}
+	}
+
+	for i, ln := range lns {
+		if n := len(ln.connCh); n != dials/len(lns) {
+			t.Fatalf("listener %d got %d of %d dials, want %d", i, n, dials, dials/len(lns))
+		}
+	}
+
+	// Dials go to the listeners which are left open
+	lns[0].Close()
+	if _, err := f.Dial("client", "svc"); err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	if n := len(lns[1].connCh); n != dials/len(lns)+1 {
+		t.Fatalf("listener 1 got %d dials, want %d", n, dials/len(lns)+1)
+	}
+}
@@ -0,0 +1,75 @@
+package memnet
+
+import "context"
+
+// WithReadFairness makes the concurrent Reads of a conn take turns: they
+// are served in the order they arrived, and each takes at most
+// maxPerRead bytes before the next one's turn, so a reader with a big
+// buffer can't starve the others of a large payload. The other ways of
+// reading, like ReadMulti and CopyN, don't take turns.
+func WithReadFairness(maxPerRead int) Option {
+	return func(l *Listener) {
+		l.readFairness = maxPerRead
+	}
+}
+
+// line is a queue of tickets which makes waiters take turns in the order
+// they arrived. It is guarded by the mutex of its ring.
+type line struct {
+	next    uint64
+	tickets []uint64
+}
+
+// join hands out the ticket at the end of the line.
+func (l *line) join() uint64 {
+	l.next++
+	l.tickets = append(l.tickets, l.next)
+	return l.next
+}
+
+// first reports whether ticket is at the front of the line.
+func (l *line) first(ticket uint64) bool {
+	return len(l.tickets) > 0 && l.tickets[0] == ticket
+}
+
+// leave takes ticket out of the line, wherever it is.
+func (l *line) leave(ticket uint64) {
+	for i, t := range l.tickets {
+		if t == ticket {
+			l.tickets = append(l.tickets[:i], l.tickets[i+1:]...)
+			return
+		}
+	}
+}
+
+// awaitTurn queues up a read and waits till it is first in line. It
+// returns the func which ends the turn, or fails like awaitReadable
+// while the read is waiting in line. rb.mu must be held.
+func (rb *ringBuff) awaitTurn(ctx context.Context) (func(), error) {
+	ticket := rb.rdline.join()
+
+	leave := func() {
+		rb.rdline.leave(ticket)
+		rb.rdwait.Broadcast()
+	}
+
+	for !rb.rdline.first(ticket) {
+		var err error
+		switch {
+		case rb.closed:
+			err = rb.errClosedPipe()
+		case rb.rdtimeout:
+			err = errTimeout
+		default:
+			err = ctx.Err()
+		}
+
+		if err != nil {
+			leave()
+			return nil, err
+		}
+
+		rb.wait(&rb.rdwait, rb.rdid)
+	}
+	return leave, nil
+}
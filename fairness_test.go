@@ -0,0 +1,84 @@
+package memnet
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestReadFairness(t *testing.T) {
+	const fair, payload = 100, 4000
+
+	ln, err := Listen(dLnOptn.c, payload, dLnOptn.a, WithReadFairness(fair))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	var mu sync.Mutex
+	var reads [2]int
+	var output [2][]byte
+
+	var wg sync.WaitGroup
+	for i := range reads {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			buf := make([]byte, payload)
+			for {
+				n, err := remote.Read(buf)
+				if n > fair {
+					t.Errorf("reader %d: Read = %d, want at most %d", i, n, fair)
+				}
+
+				mu.Lock()
+				if n > 0 {
+					reads[i]++
+					output[i] = append(output[i], buf[:n]...)
+				}
+				mu.Unlock()
+
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					t.Errorf(errReadRemoteConn, err.Error())
+					return
+				}
+			}
+		}(i)
+	}
+
+	// Both readers are in line before the payload arrives at once
+	waitWaiters(t, remote.(*conn).r.(*ringBuff), 2, 0)
+
+	input := bytes.Repeat([]byte("0123456789"), payload/10)
+	if _, err := local.Write(input); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+	local.Close()
+	wg.Wait()
+
+	// Only the readers waiting in line are served in order, one which
+	// is yet to call Read again misses its turn, so the exact split
+	// isn't known. Both were in line before the payload came, though.
+	if reads[0] == 0 || reads[1] == 0 {
+		t.Fatalf("readers read %d and %d times, want both to read", reads[0], reads[1])
+	}
+
+	if n := len(output[0]) + len(output[1]); n != payload {
+		t.Fatalf("readers got %d bytes, want %d", n, payload)
+	}
+}
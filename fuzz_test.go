@@ -0,0 +1,124 @@
+//go:build go1.18
+// +build go1.18
+
+package memnet
+
+import (
+	"bytes"
+	"context"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+)
+
+// FuzzConn feeds the payload through a conn pair in randomly split
+// writes and reads, with deadlines which expire at random in between,
+// and checks the payload comes out intact. The first byte picks the size
+// of the ring, small sizes make the writes wrap around it a lot.
+func FuzzConn(f *testing.F) {
+	f.Add([]byte("\x03hello, world"))
+	f.Add(append([]byte{0}, bytes.Repeat([]byte("0123456789"), 10)...))
+	f.Add([]byte("\x0fa"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) == 0 {
+			return
+		}
+
+		size, payload := 1+int(data[0]%16), data[1:]
+
+		h := fnv.New64a()
+		h.Write(data)
+		seed := int64(h.Sum64())
+
+		ln, err := Listen(1, size, dLnOptn.a)
+		if err != nil {
+			t.Fatalf(errMemListener, err.Error())
+		}
+		defer ln.Close()
+
+		// The sequence check panics on bytes which got out of order
+		local, err := ln.DialContext(context.Background(), WithSequenceCheck())
+		if err != nil {
+			t.Fatalf(errMemServer, err.Error())
+		}
+
+		remote, err := ln.Accept()
+		if err != nil {
+			t.Fatalf(errAcceptMemConn, err.Error())
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- fuzzWrite(local, payload, rand.New(rand.NewSource(seed)))
+		}()
+
+		output, err := fuzzRead(remote, rand.New(rand.NewSource(^seed)))
+		if err != nil {
+			t.Fatalf(errReadRemoteConn, err.Error())
+		}
+
+		if err := <-errCh; err != nil {
+			t.Fatalf(errWriteLocalConn, err.Error())
+		}
+
+		if !bytes.Equal(payload, output) {
+			t.Fatalf(errIOMismatched, payload, output)
+		}
+	})
+}
+
+// isTimeout reports whether err is a net.Error timeout.
+func isTimeout(err error) bool {
+	nerr, ok := err.(net.Error)
+	return ok && nerr.Timeout()
+}
+
+// fuzzWrite writes p in random splits and closes c. A write now and then
+// runs into a deadline which has passed, it is retried with the bytes
+// which didn't make it.
+func fuzzWrite(c net.Conn, p []byte, rnd *rand.Rand) error {
+	for len(p) > 0 {
+		chunk := 1 + rnd.Intn(len(p))
+
+		if rnd.Intn(4) == 0 {
+			c.SetWriteDeadline(time.Now().Add(-time.Second))
+		}
+
+		n, err := c.Write(p[:chunk])
+		c.SetWriteDeadline(time.Time{})
+
+		if err != nil && !isTimeout(err) {
+			return err
+		}
+		p = p[n:]
+	}
+	return c.Close()
+}
+
+// fuzzRead reads from c till io.EOF in random sizes, with deadlines
+// passing now and then like fuzzWrite does.
+func fuzzRead(c net.Conn, rnd *rand.Rand) ([]byte, error) {
+	var output []byte
+	for {
+		buf := make([]byte, 1+rnd.Intn(32))
+
+		if rnd.Intn(4) == 0 {
+			c.SetReadDeadline(time.Now().Add(-time.Second))
+		}
+
+		n, err := c.Read(buf)
+		c.SetReadDeadline(time.Time{})
+
+		output = append(output, buf[:n]...)
+		if err == io.EOF {
+			return output, nil
+		}
+		if err != nil && !isTimeout(err) {
+			return output, err
+		}
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/ataul443/memnet
 
-go 1.14
+go 1.15
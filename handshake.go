@@ -0,0 +1,16 @@
+package memnet
+
+import "net"
+
+// WithHandshake makes the listener run fn on the two ends of every conn
+// as it is paired up, on the dialing goroutine before the dial returns,
+// like an abstract TLS or auth handshake. fn can set up per-conn state,
+// or reject the pairing by returning an error: the dial then fails with
+// it, and the accepted end reads it once it drained what fn wrote, like
+// after CloseWithError. Bytes fn exchanges through the ends have to fit
+// in their buffers, as nobody else reads them yet.
+func WithHandshake(fn func(local, remote net.Conn) error) Option {
+	return func(l *Listener) {
+		l.handshake = fn
+	}
+}
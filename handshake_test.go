@@ -0,0 +1,78 @@
+package memnet
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestHandshake(t *testing.T) {
+	errDenied := errors.New("handshake: denied")
+
+	// The second pairing is rejected
+	var pairings int
+
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a,
+		WithHandshake(func(local, remote net.Conn) error {
+			if _, err := local.Write([]byte("hi")); err != nil {
+				return err
+			}
+
+			p := make([]byte, 2)
+			if _, err := io.ReadFull(remote, p); err != nil {
+				return err
+			}
+
+			if pairings++; pairings == 2 {
+				return errDenied
+			}
+			return nil
+		}))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	// The handshake consumed its own bytes
+	if _, err := local.Write([]byte("ok")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	p := make([]byte, 2)
+	if _, err := io.ReadFull(remote, p); err != nil || string(p) != "ok" {
+		t.Fatalf("io.ReadFull(remote) = %q, %v, want %q, nil", p, err, "ok")
+	}
+
+	// Both ends see the rejection
+	if _, err := ln.Dial(); err != errDenied {
+		t.Fatalf("rejected dial = _, %v, want %v", err, errDenied)
+	}
+
+	remote, err = ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	if _, err := remote.Read(p); err != errDenied {
+		t.Fatalf("remote.Read of a rejected conn = _, %v, want %v", err, errDenied)
+	}
+
+	if _, err := remote.Write(p); err == nil {
+		t.Fatalf("remote.Write of a rejected conn = _, nil, want an error")
+	}
+
+	if n := ln.NumConns(); n != 1 {
+		t.Fatalf("ln.NumConns() = %d, want %d", n, 1)
+	}
+}
@@ -0,0 +1,84 @@
+package memnet
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithLatencyHistogram makes the Stats of both ends of the dialed conn
+// report how long their Reads and Writes took, which shows whether the
+// latency and jitter injected come out as intended. A Read's time
+// includes the wait for data to arrive.
+func WithLatencyHistogram() DialOption {
+	return func(o *dialOptions) { o.histogram = true }
+}
+
+// latencyBuckets are the upper bounds of the buckets of the histograms.
+var latencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// LatencyHistogram counts operations by how long they took. Counts[i]
+// is the number of those which took less than Bounds[i], and at least
+// Bounds[i-1] for i > 0. The extra last count is of those which took
+// Bounds[len(Bounds)-1] or longer.
+type LatencyHistogram struct {
+	Bounds []time.Duration
+	Counts []int64
+}
+
+// histogram is the live form of a LatencyHistogram, its counts are
+// accessed atomically. A nil *histogram records nothing.
+type histogram struct {
+	counts []int64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]int64, len(latencyBuckets)+1)}
+}
+
+// since records an operation which started at start.
+func (h *histogram) since(start time.Time) {
+	if h == nil {
+		return
+	}
+
+	d := time.Since(start)
+
+	i := 0
+	for i < len(latencyBuckets) && d >= latencyBuckets[i] {
+		i++
+	}
+	atomic.AddInt64(&h.counts[i], 1)
+}
+
+// snapshot returns a copy of the counts, nil for a nil *histogram.
+func (h *histogram) snapshot() *LatencyHistogram {
+	if h == nil {
+		return nil
+	}
+
+	lh := &LatencyHistogram{
+		Bounds: append([]time.Duration(nil), latencyBuckets...),
+		Counts: make([]int64, len(h.counts)),
+	}
+	for i := range h.counts {
+		lh.Counts[i] = atomic.LoadInt64(&h.counts[i])
+	}
+	return lh
+}
+
+// reset zeroes the counts.
+func (h *histogram) reset() {
+	if h == nil {
+		return
+	}
+
+	for i := range h.counts {
+		atomic.StoreInt64(&h.counts[i], 0)
+	}
+}
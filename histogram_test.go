@@ -0,0 +1,69 @@
+package memnet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	// The ring fits all the writes, so no read is cut short at its end
+	ln, err := Listen(dLnOptn.c, 64, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	local, err := ln.DialContext(context.Background(),
+		WithLatency(20*time.Millisecond), WithLatencyHistogram())
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	const writes = 5
+	for i := 0; i < writes; i++ {
+		if _, err := local.Write([]byte("ping")); err != nil {
+			t.Fatalf(errWriteLocalConn, err.Error())
+		}
+
+		if _, err := remote.Read(make([]byte, 4)); err != nil {
+			t.Fatalf(errReadRemoteConn, err.Error())
+		}
+	}
+
+	// All the reads wait for the latency, which is in the bucket of
+	// [10ms, 100ms)
+	h := remote.(*conn).Stats().ReadLatency
+	for i, n := range h.Counts {
+		want := int64(0)
+		if i == 3 {
+			want = writes
+		}
+
+		if n != want {
+			t.Fatalf("ReadLatency.Counts = %v, want all %d in bucket %d", h.Counts, writes, 3)
+		}
+	}
+
+	if h.Bounds[2] != 10*time.Millisecond || h.Bounds[3] != 100*time.Millisecond {
+		t.Fatalf("ReadLatency.Bounds = %v, want bucket 3 to be [10ms, 100ms)", h.Bounds)
+	}
+
+	var wrote int64
+	for _, n := range local.(*conn).Stats().WriteLatency.Counts {
+		wrote += n
+	}
+	if wrote != writes {
+		t.Fatalf("WriteLatency has %d samples, want %d", wrote, writes)
+	}
+
+	// Histograms cost nothing unless asked for
+	if plain, _, err := memConnServe(); err != nil || plain.(*conn).Stats().ReadLatency != nil {
+		t.Fatalf("Stats().ReadLatency of a plain conn isn't nil")
+	}
+}
@@ -0,0 +1,18 @@
+package memnet
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// Transport returns an *http.Transport which dials every request into ln,
+// whatever the host of the request URL is. Idle connections are kept for
+// reuse like with any http.Transport.
+func Transport(ln *Listener) *http.Transport {
+	return &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		},
+	}
+}
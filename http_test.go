@@ -0,0 +1,89 @@
+package memnet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"testing"
+	"time"
+)
+
+func TestHTTPTransport(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, 1024, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.Path)
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	client := &http.Client{Transport: Transport(ln)}
+
+	var reused []bool
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			reused = append(reused, info.Reused)
+		},
+	}
+
+	for _, path := range []string{"/first", "/second"} {
+		req, err := http.NewRequest("GET", "http://memnet"+path, nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest = _, %v", err)
+		}
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("client.Do(%s) = _, %v", path, err)
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("could not read response body: %v", err)
+		}
+
+		if string(body) != path {
+			t.Fatalf(errIOMismatched, path, body)
+		}
+	}
+
+	if len(reused) != 2 || reused[0] || !reused[1] {
+		t.Fatalf("GotConn reused = %v, want [false true]", reused)
+	}
+}
+
+func TestHTTPTransportCancel(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, 1024, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	client := &http.Client{Transport: Transport(ln)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	req, err := http.NewRequest("GET", "http://memnet/block", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest = _, %v", err)
+	}
+
+	_, err = client.Do(req.WithContext(ctx))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("client.Do = _, %v, want %v", err, context.Canceled)
+	}
+}
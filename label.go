@@ -0,0 +1,40 @@
+package memnet
+
+import "net"
+
+// WithLabel routes the dialed conn to the accept queue of label, like
+// the server name of a TLS handshake picks a virtual host. The conn is
+// only returned by AcceptFor(label), Accept only returns the conns which
+// were dialed without a label.
+func WithLabel(label string) DialOption {
+	return func(o *dialOptions) { o.label = label }
+}
+
+// AcceptFor is like Accept but waits for a conn dialed WithLabel(label).
+// Each label has an accept queue of its own, as long as the listener's.
+func (l *Listener) AcceptFor(label string) (net.Conn, error) {
+	return l.accept(label)
+}
+
+// queue returns the accept queue of label, creating it on first use, and
+// the channel closed once Reconfigure replaced it. The empty label is the
+// queue of Accept.
+func (l *Listener) queue(label string) (chan net.Conn, chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if label == "" {
+		return l.connCh, l.rewire
+	}
+
+	if l.labeled == nil {
+		l.labeled = make(map[string]chan net.Conn)
+	}
+
+	ch, ok := l.labeled[label]
+	if !ok {
+		ch = make(chan net.Conn, cap(l.connCh))
+		l.labeled[label] = ch
+	}
+	return ch, l.rewire
+}
@@ -0,0 +1,61 @@
+package memnet
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestAcceptFor(t *testing.T) {
+	ln, err := Listen(2, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	dial := func(label string, opts ...DialOption) {
+		c, err := ln.DialContext(context.Background(), opts...)
+		if err != nil {
+			t.Fatalf(errMemServer, err.Error())
+		}
+
+		if _, err := c.Write([]byte(label)); err != nil {
+			t.Fatalf(errWriteLocalConn, err.Error())
+		}
+	}
+
+	dial("api", WithLabel("api"))
+	dial("web", WithLabel("web"))
+	dial("api", WithLabel("api"))
+	dial("none")
+
+	accept := func(want string, accept func() (net.Conn, error)) {
+		c, err := accept()
+		if err != nil {
+			t.Fatalf(errAcceptMemConn, err.Error())
+		}
+
+		p := make([]byte, 8)
+		n, err := c.Read(p)
+		if err != nil {
+			t.Fatalf(errReadRemoteConn, err)
+		}
+
+		if got := string(p[:n]); got != want {
+			t.Fatalf("accepted the conn of %q, want %q", got, want)
+		}
+	}
+
+	forLabel := func(label string) func() (net.Conn, error) {
+		return func() (net.Conn, error) { return ln.AcceptFor(label) }
+	}
+
+	accept("web", forLabel("web"))
+	accept("none", ln.Accept)
+	accept("api", forLabel("api"))
+	accept("api", forLabel("api"))
+
+	ln.Close()
+	if _, err := ln.AcceptFor("web"); err == nil {
+		t.Fatal("ln.AcceptFor succeeded on a closed listener")
+	}
+}
@@ -0,0 +1,207 @@
+package memnet
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// WithLatency delays every write in both directions by d before its
+// bytes reach the peer, so a request and its response take 2*d. The
+// writes don't wait for it, their bytes are held back from the peer's
+// reads, so several writes in a row all arrive d after they were made.
+func WithLatency(d time.Duration) DialOption {
+	return func(o *dialOptions) { o.latency = d }
+}
+
+// WithLatencyJitter is WithLatency with a delay drawn for every write
+// from [base-jitter, base+jitter], which models a varying RTT. The delays
+// come from a random source seeded with seed, so a run can be replayed.
+func WithLatencyJitter(base, jitter time.Duration, seed int64) DialOption {
+	return func(o *dialOptions) {
+		o.latency = base
+		o.jitter = newJitter(jitter, seed)
+	}
+}
+
+// jitter draws the varying part of the latency, it is shared by both
+// directions of a conn.
+type jitter struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+	d   time.Duration
+}
+
+func newJitter(d time.Duration, seed int64) *jitter {
+	if d <= 0 {
+		return nil
+	}
+	return &jitter{rnd: rand.New(rand.NewSource(seed)), d: d}
+}
+
+// draw returns base moved by a random amount of up to the jitter, but
+// never below zero. A nil *jitter returns base.
+func (j *jitter) draw(base time.Duration) time.Duration {
+	if j == nil {
+		return base
+	}
+
+	j.mu.Lock()
+	d := base - j.d + time.Duration(j.rnd.Int63n(int64(2*j.d)+1))
+	j.mu.Unlock()
+
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// WithBandwidth throttles both directions of the dialed conn to
+// bytesPerSec: a write of n bytes takes n/bytesPerSec seconds to send,
+// after the writes before it, on top of the latency. A bytesPerSec <= 0
+// means no throttle.
+func WithBandwidth(bytesPerSec int64) DialOption {
+	return func(o *dialOptions) { o.bandwidth = bytesPerSec }
+}
+
+// arrival is a write to the ring on its way to the readers.
+type arrival struct {
+	n      int
+	landed bool
+}
+
+// transitDelay returns how long n bytes written now take to reach the
+// readers: the latency, and the time the bandwidth takes to send them
+// after the bytes written before. rb.mu must be held.
+func (rb *ringBuff) transitDelay(n int) time.Duration {
+	d := rb.jitter.draw(rb.latency)
+	if rb.bandwidth <= 0 {
+		return d
+	}
+
+	now := rb.clock.Now()
+	if rb.linkFree.Before(now) {
+		rb.linkFree = now
+	}
+	rb.linkFree = rb.linkFree.Add(time.Duration(int64(n) * int64(time.Second) / rb.bandwidth))
+	return d + rb.linkFree.Sub(now)
+}
+
+// send puts the n bytes just written to buff on their way to the
+// readers. The writer doesn't wait for them to arrive, the readers do,
+// and they get them in the order they were written. rb.mu must be held.
+func (rb *ringBuff) send(n int) {
+	d := rb.transitDelay(n)
+	if d <= 0 && len(rb.transit) == 0 {
+		rb.watchdogWrote()
+		rb.deliver()
+		return
+	}
+
+	a := &arrival{n: n, landed: d <= 0}
+	rb.transit = append(rb.transit, a)
+	rb.intransit += n
+
+	if d > 0 {
+		rb.clock.AfterFunc(d, func() {
+			rb.mu.Lock()
+			defer rb.mu.Unlock()
+
+			a.landed = true
+			rb.land()
+		})
+	}
+}
+
+// land hands the writes which arrived to the readers, those which
+// arrived ahead of an earlier one wait for it. rb.mu must be held.
+func (rb *ringBuff) land() {
+	n := 0
+	for len(rb.transit) > 0 && rb.transit[0].landed {
+		n += rb.transit[0].n
+		rb.transit = rb.transit[1:]
+	}
+
+	if n > 0 {
+		rb.intransit -= n
+		rb.watchdogWrote()
+		rb.deliver()
+	}
+}
+
+// pause holds up a write for d, for the rings of zero capacity and the
+// high priority lane, which can't keep bytes in transit. It fails like
+// the write on close, deadlines and ctx. rb.mu must be held.
+func (rb *ringBuff) pause(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	done := false
+	t := rb.clock.AfterFunc(d, func() {
+		rb.mu.Lock()
+		defer rb.mu.Unlock()
+
+		done = true
+		rb.wrwait.Broadcast()
+	})
+	defer t.Stop()
+
+	for !done {
+		if rb.closed || rb.writeClosed {
+			return rb.errClosedPipe()
+		}
+
+		if rb.wrtimeout {
+			return errTimeout
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rb.wait(&rb.wrwait, rb.wrid)
+	}
+	return nil
+}
+
+// ConnInfo describes the simulated network conditions of a conn, in the
+// spirit of TCP_INFO.
+type ConnInfo struct {
+	// BytesInFlight is the number of bytes written by the conn which
+	// the peer hasn't read yet
+	BytesInFlight int
+
+	// RTT is the round trip time from the configured latencies
+	RTT time.Duration
+}
+
+// Info returns the current ConnInfo of the conn.
+func (c *conn) Info() ConnInfo {
+	var info ConnInfo
+
+	wr := c.w.(*ringBuff)
+	wr.mu.Lock()
+	info.BytesInFlight = wr.unread()
+	info.RTT = wr.latency
+	wr.mu.Unlock()
+
+	rd := c.r.(*ringBuff)
+	rd.mu.Lock()
+	info.RTT += rd.latency
+	rd.mu.Unlock()
+
+	return info
+}
+
+// SetLatency changes the latency of both directions of the conn to d,
+// like a link which degrades or recovers. It applies to the writes which
+// start after it, on either end. The jitter of WithLatencyJitter stays.
+func (c *conn) SetLatency(d time.Duration) {
+	for _, rb := range []*ringBuff{c.w.(*ringBuff), c.r.(*ringBuff)} {
+		rb.mu.Lock()
+		rb.latency = d
+		rb.mu.Unlock()
+	}
+}
@@ -0,0 +1,267 @@
+package memnet
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConnInfoLatency(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	latency := 20 * time.Millisecond
+	local, err := ln.DialContext(context.Background(), WithLatency(latency))
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	for _, c := range []*conn{local.(*conn), remote.(*conn)} {
+		if rtt := c.Info().RTT; rtt != 2*latency {
+			t.Fatalf("Info().RTT = %v, want %v", rtt, 2*latency)
+		}
+	}
+
+	start := time.Now()
+	if _, err := local.Write([]byte("ping")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if n := local.(*conn).Info().BytesInFlight; n != 4 {
+		t.Fatalf("Info().BytesInFlight = %d, want %d", n, 4)
+	}
+
+	p := make([]byte, 4)
+	if _, err := remote.Read(p); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	if _, err := remote.Write(p); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if _, err := local.Read(p); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	if d := time.Since(start); d < 2*latency {
+		t.Fatalf("round trip took %v, want >= %v", d, 2*latency)
+	}
+
+	if n := local.(*conn).Info().BytesInFlight; n != 0 {
+		t.Fatalf("Info().BytesInFlight = %d, want %d", n, 0)
+	}
+}
+
+func TestLatencyJitter(t *testing.T) {
+	const base, jit = 10 * time.Millisecond, 4 * time.Millisecond
+
+	draws := func(seed int64) []time.Duration {
+		j := newJitter(jit, seed)
+		ds := make([]time.Duration, 50)
+		for i := range ds {
+			ds[i] = j.draw(base)
+		}
+		return ds
+	}
+
+	ds := draws(7)
+	seen := make(map[time.Duration]bool)
+	for _, d := range ds {
+		if d < base-jit || d > base+jit {
+			t.Fatalf("drew %v, want within [%v, %v]", d, base-jit, base+jit)
+		}
+		seen[d] = true
+	}
+
+	if len(seen) < 2 {
+		t.Fatalf("drew %v every time, want varying delays", ds[0])
+	}
+
+	for i, d := range draws(7) {
+		if d != ds[i] {
+			t.Fatalf("draw %d = %v with the same seed, want %v", i, d, ds[i])
+		}
+	}
+
+	// Bigger jitter than base never goes negative
+	j := newJitter(base, 1)
+	for i := 0; i < 50; i++ {
+		if d := j.draw(base / 2); d < 0 {
+			t.Fatalf("drew %v, want >= 0", d)
+		}
+	}
+}
+
+func TestLatencyJitterWrite(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	const base, jit = 20 * time.Millisecond, 5 * time.Millisecond
+	local, err := ln.DialContext(context.Background(), WithLatencyJitter(base, jit, 1))
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	start := time.Now()
+	if _, err := local.Write([]byte("a")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if _, err := remote.Read(make([]byte, 1)); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	if d := time.Since(start); d < base-jit {
+		t.Fatalf("write arrived after %v, want >= %v", d, base-jit)
+	}
+}
+
+func TestConnSetLatency(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	local, err := ln.DialContext(context.Background(), WithLatency(5*time.Millisecond))
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	pingPong := func() time.Duration {
+		t.Helper()
+		start := time.Now()
+		for _, dir := range [][2]net.Conn{{local, remote}, {remote, local}} {
+			if _, err := dir[0].Write([]byte("ping")); err != nil {
+				t.Fatalf(errWriteLocalConn, err.Error())
+			}
+
+			if _, err := io.ReadFull(dir[1], make([]byte, 4)); err != nil {
+				t.Fatalf(errReadRemoteConn, err.Error())
+			}
+		}
+		return time.Since(start)
+	}
+
+	if rtt := pingPong(); rtt < 10*time.Millisecond || rtt >= 50*time.Millisecond {
+		t.Fatalf("round trip took %v at the dialed latency, want about %v", rtt, 10*time.Millisecond)
+	}
+
+	// The link degrades, seen from the accepted end too
+	local.(*conn).SetLatency(50 * time.Millisecond)
+
+	if rtt := remote.(*conn).Info().RTT; rtt != 100*time.Millisecond {
+		t.Fatalf("remote Info().RTT = %v, want %v", rtt, 100*time.Millisecond)
+	}
+
+	if rtt := pingPong(); rtt < 100*time.Millisecond {
+		t.Fatalf("round trip took %v after SetLatency, want at least %v", rtt, 100*time.Millisecond)
+	}
+}
+
+func TestLatencyDelaysDelivery(t *testing.T) {
+	clk := &fakeClock{}
+
+	ln, err := Listen(dLnOptn.c, 64, dLnOptn.a, withClock(clk))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	const d = time.Second
+	local, err := ln.DialContext(context.Background(), WithLatency(d), WithBandwidth(100))
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	rb := remote.(*conn).r.(*ringBuff)
+	arrived := func() int {
+		rb.mu.Lock()
+		defer rb.mu.Unlock()
+		return rb.buffered() - rb.intransit
+	}
+
+	// The writes go out back to back without waiting for the link,
+	// 10 bytes at 100 B/s take 100ms each to send
+	for _, s := range []string{"0123456789", "abcdefghij"} {
+		if _, err := local.Write([]byte(s)); err != nil {
+			t.Fatalf(errWriteLocalConn, err.Error())
+		}
+	}
+
+	if n := local.(*conn).Info().BytesInFlight; n != 20 {
+		t.Fatalf("Info().BytesInFlight = %d, want 20", n)
+	}
+
+	for _, step := range []struct {
+		advance time.Duration
+		arrived int
+	}{
+		{d, 0},
+		{100 * time.Millisecond, 10},
+		{50 * time.Millisecond, 10},
+		{50 * time.Millisecond, 20},
+	} {
+		clk.Advance(step.advance)
+		if n := arrived(); n != step.arrived {
+			t.Fatalf("%d bytes arrived at %v, want %d", n, clk.Now().Sub(time.Unix(0, 0)), step.arrived)
+		}
+	}
+
+	output := make([]byte, 20)
+	if _, err := io.ReadFull(remote, output); err != nil || string(output) != "0123456789abcdefghij" {
+		t.Fatalf("io.ReadFull = %q, %v", output, err)
+	}
+}
+
+func TestLatencyUnbufferedWriteDeadline(t *testing.T) {
+	clk := &fakeClock{}
+
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, withClock(clk))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	local, err := ln.DialContext(context.Background(), WithBufferSize(0), WithLatency(time.Hour))
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	if _, err := ln.Accept(); err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	// Without a buffer to hold the bytes back in, the write waits for
+	// the latency, but not past its deadline
+	local.SetWriteDeadline(time.Now().Add(20 * time.Millisecond))
+	if n, err := local.Write([]byte("a")); n != 0 || err != errTimeout {
+		t.Fatalf("local.Write = %d, %v, want 0, %v", n, err, errTimeout)
+	}
+}
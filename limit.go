@@ -0,0 +1,66 @@
+package memnet
+
+import (
+	"io"
+	"net"
+	"sync"
+)
+
+// LimitListener returns a listener which accepts at most n conns from ln
+// at a time: Accept blocks while n of them are live and resumes as they
+// close. A memnet conn stops being live once either of its ends is
+// closed, like for NumConns, and is returned as it is. Other conns are
+// wrapped and stop being live once they are closed.
+func LimitListener(ln net.Listener, n int) net.Listener {
+	return &limitListener{
+		Listener: ln,
+		sem:      make(chan struct{}, n),
+		done:     make(chan struct{}),
+	}
+}
+
+type limitListener struct {
+	net.Listener
+	sem       chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-l.done:
+		return nil, io.ErrClosedPipe
+	}
+
+	c, err := l.Listener.Accept()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+
+	release := func() { <-l.sem }
+
+	if mc, ok := unwrapConn(c); ok {
+		mc.pair.onClose(release)
+		return c, nil
+	}
+	return &limitConn{Conn: c, release: release}, nil
+}
+
+func (l *limitListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+type limitConn struct {
+	net.Conn
+	release   func()
+	closeOnce sync.Once
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(c.release)
+	return err
+}
@@ -0,0 +1,76 @@
+package memnet
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLimitListener(t *testing.T) {
+	ln, err := Listen(4, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	lim := LimitListener(ln, 2)
+	defer lim.Close()
+
+	var locals []net.Conn
+	for i := 0; i < 3; i++ {
+		local, err := ln.Dial()
+		if err != nil {
+			t.Fatalf(errMemServer, err.Error())
+		}
+		locals = append(locals, local)
+	}
+
+	for i := 0; i < 2; i++ {
+		remote, err := lim.Accept()
+		if err != nil {
+			t.Fatalf(errAcceptMemConn, err.Error())
+		}
+
+		if !SamePeer(locals[i], remote) {
+			t.Fatalf("accept %d didn't return the memnet conn as it is", i)
+		}
+	}
+
+	accepted := make(chan error, 1)
+	go func() {
+		_, err := lim.Accept()
+		accepted <- err
+	}()
+
+	select {
+	case err := <-accepted:
+		t.Fatalf("third lim.Accept = _, %v, want it to block", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Closing either end of a conn frees its slot
+	locals[0].Close()
+
+	select {
+	case err := <-accepted:
+		if err != nil {
+			t.Fatalf(errAcceptMemConn, err.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("third lim.Accept still blocks after a conn closed")
+	}
+
+	go func() {
+		_, err := lim.Accept()
+		accepted <- err
+	}()
+
+	lim.Close()
+	select {
+	case err := <-accepted:
+		if err == nil {
+			t.Fatalf("lim.Accept after Close = _, nil, want an error")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("lim.Accept still blocks after Close")
+	}
+}
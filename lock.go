@@ -0,0 +1,31 @@
+package memnet
+
+// LockWrite takes the conn's write lock, which lets a caller composing a
+// frame out of several Writes keep other writers from interleaving with
+// it. The lock is advisory, like a mutex guarding a bufio.Writer: it
+// only holds back the writers which take it too, a plain Write is not
+// excluded.
+//
+// The lock is not reentrant and it is held across blocking Writes. A
+// holder which waits for the peer to do something, while the peer waits
+// for a writer of this conn which is queued on the lock, deadlocks. So
+// does a holder which never calls UnlockWrite, keep the section short.
+func (c *conn) LockWrite() {
+	c.wrlock.Lock()
+}
+
+// UnlockWrite releases the write lock taken by LockWrite.
+func (c *conn) UnlockWrite() {
+	c.wrlock.Unlock()
+}
+
+// LockRead takes the conn's read lock, the counterpart of LockWrite for
+// a caller parsing a frame out of several Reads. The same caveats apply.
+func (c *conn) LockRead() {
+	c.rdlock.Lock()
+}
+
+// UnlockRead releases the read lock taken by LockRead.
+func (c *conn) UnlockRead() {
+	c.rdlock.Unlock()
+}
@@ -0,0 +1,54 @@
+package memnet
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestConnLockWrite(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	lc := local.(*conn)
+
+	lc.LockWrite()
+	if _, err := lc.Write([]byte("he")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	wrote := make(chan error, 1)
+	go func() {
+		lc.LockWrite()
+		defer lc.UnlockWrite()
+
+		_, err := lc.Write([]byte("world"))
+		wrote <- err
+	}()
+
+	select {
+	case err := <-wrote:
+		t.Fatalf("second writer wrote (%v) while the lock was held", err)
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	if _, err := lc.Write([]byte("llo")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+	lc.UnlockWrite()
+
+	if err := <-wrote; err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	output := make([]byte, len("helloworld"))
+	if _, err := io.ReadFull(remote, output); err != nil {
+		t.Fatalf(errReadRemoteConn, err)
+	}
+
+	if string(output) != "helloworld" {
+		t.Fatalf(errIOMismatched, "helloworld", output)
+	}
+}
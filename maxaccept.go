@@ -0,0 +1,27 @@
+package memnet
+
+import "sync/atomic"
+
+// WithMaxAccepts makes the listener close itself once it accepted n
+// conns, which suits single use test servers. Later dials fail like
+// dials to a closed listener, and so do the Accepts of dials which were
+// still queued.
+func WithMaxAccepts(n int) Option {
+	return func(l *Listener) {
+		l.maxAccepts = int32(n)
+	}
+}
+
+// countAccept counts an accept against the limit and closes the listener
+// once it is reached. It reports false if the limit was reached already.
+func (l *Listener) countAccept() bool {
+	if l.maxAccepts <= 0 {
+		return true
+	}
+
+	n := atomic.AddInt32(&l.naccepts, 1)
+	if n == l.maxAccepts {
+		l.Close()
+	}
+	return n <= l.maxAccepts
+}
@@ -0,0 +1,50 @@
+package memnet
+
+import (
+	"io"
+	"testing"
+)
+
+func TestWithMaxAccepts(t *testing.T) {
+	ln, err := Listen(2, dLnOptn.t, dLnOptn.a, WithMaxAccepts(1))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	first, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	// Queued before the limit was reached, but never accepted
+	queued, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	if _, err := ln.Dial(); err != io.ErrClosedPipe {
+		t.Fatalf("ln.Dial = _, %v, want %v", err, io.ErrClosedPipe)
+	}
+
+	if _, err := ln.Accept(); err != io.ErrClosedPipe {
+		t.Fatalf("ln.Accept = _, %v, want %v", err, io.ErrClosedPipe)
+	}
+
+	if _, err := queued.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("queued.Read = _, %v, want %v", err, io.EOF)
+	}
+
+	// The accepted conn is not affected
+	if _, err := first.Write([]byte("a")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if _, err := remote.Read(make([]byte, 1)); err != nil {
+		t.Fatalf(errReadRemoteConn, err)
+	}
+}
@@ -1,10 +1,14 @@
 package memnet
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -16,6 +20,18 @@ func (a addr) Network() string { return a.address }
 
 func (a addr) String() string { return a.address }
 
+type netErrTemporary struct {
+	error
+}
+
+func (net netErrTemporary) Timeout() bool {
+	return false
+}
+
+func (net netErrTemporary) Temporary() bool {
+	return true
+}
+
 type netErrTimeout struct {
 	error
 }
@@ -28,15 +44,40 @@ func (net netErrTimeout) Temporary() bool {
 	return false
 }
 
+// Unwrap lets errors.Is match timeouts against os.ErrDeadlineExceeded.
+func (net netErrTimeout) Unwrap() error {
+	return net.error
+}
+
+// netErrPermanent is a net.Error which is neither a timeout nor
+// temporary.
+type netErrPermanent struct {
+	error
+}
+
+func (net netErrPermanent) Timeout() bool {
+	return false
+}
+
+func (net netErrPermanent) Temporary() bool {
+	return false
+}
+
+// Unwrap lets errors.Is match the wrapped error, like syscall.ECONNRESET.
+func (net netErrPermanent) Unwrap() error {
+	return net.error
+}
+
 var (
 	errClosed            = fmt.Errorf("closed")
-	errTimeout net.Error = netErrTimeout{error: fmt.Errorf("i/o timeout")}
+	errTimeout net.Error = netErrTimeout{error: os.ErrDeadlineExceeded}
+	errReset   net.Error = netErrPermanent{os.NewSyscallError("read", syscall.ECONNRESET)}
 )
 
 type ringBuff struct {
 	buff   []byte
 	r, w   int
-	mu     sync.Mutex
+	mu     *sync.Mutex
 	rdwait sync.Cond
 	wrwait sync.Cond
 
@@ -48,8 +89,103 @@ type ringBuff struct {
 
 	closed      bool
 	writeClosed bool
+
+	// wrerr is returned to readers in place of io.EOF once the
+	// buffer drains after the writer closed with an error
+	wrerr error
+
+	// sched, when set, replaces blocking on rdwait and wrwait with
+	// yielding the scheduler turn of the reading or writing conn.
+	sched      *scheduler
+	rdid, wrid int
+
+	metrics *metrics
+
+	// delay coalesces writes, held is set while the written bytes are
+	// kept from readers until delaytimer fires or the ring fills up
+	delay      time.Duration
+	held       bool
+	delaytimer stopper
+
+	clock clock
+
+	// prio is the high priority lane, which readers drain before
+	// any of the bytes in buff
+	prio []byte
+
+	// duplex is the turn shared with the ring of the other direction
+	// in half-duplex mode, both rings then share mu as well. writing
+	// counts the writes in progress which hold the turn
+	duplex  *duplex
+	writing int
+
+	// pending is the unread rest of the write in progress on a ring
+	// of zero capacity, which works like io.Pipe, dropped is the part
+	// of it a reset discarded
+	pending []byte
+	dropped int
+
+	// latency delays each write before its bytes reach the readers,
+	// by an amount which varies with jitter
+	latency time.Duration
+	jitter  *jitter
+
+	// bandwidth throttles the writes to that many bytes per second,
+	// linkFree is when the bytes written so far are all sent
+	bandwidth int64
+	linkFree  time.Time
+
+	// transit are the writes on their way to the readers, oldest
+	// first, their intransit bytes are the last ones of buff
+	transit   []*arrival
+	intransit int
+
+	// closedErr replaces io.ErrClosedPipe when set, rsterr replaces
+	// both once the conn was reset
+	closedErr error
+	rsterr    error
+
+	// eofErr replaces io.EOF when set
+	eofErr error
+
+	// closeDelay holds back the end of the stream from readers after a
+	// Close, finHeld is set till it passed
+	closeDelay time.Duration
+	finHeld    bool
+
+	// seq verifies the order of the bytes in WithSequenceCheck mode
+	seq *seqCheck
+
+	// wd is the countdown of WithConsumerWatchdog
+	wd *watchdog
+
+	// nonblock fails the reads which would wait, see WithNonBlockingRead
+	nonblock bool
+
+	// fairness caps the bytes per read and makes the reads take turns
+	// in rdline, see WithReadFairness
+	fairness int
+	rdline   line
+
+	// wrqueue is the number of unbuffered writers which may wait in
+	// wrline to be served in order, see WithWriterQueue
+	wrqueue int
+	wrline  line
+
+	// onBackpressure is the OnBackpressure callback, saturated is set
+	// between its calls for a full ring and for room
+	onBackpressure func(buffered, cap int)
+	saturated      bool
+
+	// rdwaiters and wrwaiters count the goroutines blocked in wait
+	// on rdwait and wrwait, tests use them to spot stalls
+	rdwaiters, wrwaiters int
 }
 
+// coalesceDelay is how long written bytes are held back from readers
+// when NoDelay is off.
+const coalesceDelay = 20 * time.Millisecond
+
 func (rb *ringBuff) empty() bool {
 	return rb.r == len(rb.buff)
 }
@@ -58,23 +194,72 @@ func (rb *ringBuff) full() bool {
 	return rb.r == rb.w && rb.r < len(rb.buff)
 }
 
+// readable reports whether a reader can return bytes right away.
+func (rb *ringBuff) readable() bool {
+	return len(rb.prio) > 0 || len(rb.pending) > 0 || (rb.buffered() > rb.intransit && !rb.held)
+}
+
+// buffered returns the number of unread bytes, which live in the window
+// [rb.r, len(rb.buff)) followed by [0, rb.w) once the writes wrapped.
+func (rb *ringBuff) buffered() int {
+	if rb.empty() {
+		return 0
+	}
+
+	n := len(rb.buff) - rb.r
+	if rb.w <= rb.r {
+		n += rb.w
+	}
+	return n
+}
+
+// unread returns the number of bytes written to the ring which weren't
+// read yet, in all of its lanes.
+func (rb *ringBuff) unread() int {
+	return rb.buffered() + len(rb.prio) + len(rb.pending)
+}
+
+// snapshot returns a copy of the unread bytes in the order they'd be
+// read, leaving them in place.
+func (rb *ringBuff) snapshot() []byte {
+	b := make([]byte, 0, rb.unread())
+	b = append(b, rb.prio...)
+	b = append(b, rb.pending...)
+
+	if !rb.empty() {
+		b = append(b, rb.buff[rb.r:len(rb.buff)]...)
+		if rb.w <= rb.r {
+			b = append(b, rb.buff[:rb.w]...)
+		}
+	}
+	return b
+}
+
 func (rb *ringBuff) Close() error {
+	_, err := rb.close()
+	return err
+}
+
+// close closes the ring and returns the number of unread bytes which
+// were discarded.
+func (rb *ringBuff) close() (int, error) {
 	rb.mu.Lock()
 	defer rb.mu.Unlock()
 
 	if rb.closed {
-		return io.ErrClosedPipe
+		return 0, io.ErrClosedPipe
 	}
 
 	rb.closed = true
+	rb.duplex.release(rb)
 
 	// Signal all blocked readers and writers
 	rb.rdwait.Broadcast()
 	rb.wrwait.Broadcast()
-	return nil
+	return rb.unread(), nil
 }
 
-func (rb *ringBuff) closeWrite() error {
+func (rb *ringBuff) closeWrite(err error) error {
 	rb.mu.Lock()
 	defer rb.mu.Unlock()
 
@@ -83,6 +268,9 @@ func (rb *ringBuff) closeWrite() error {
 	}
 
 	rb.writeClosed = true
+	rb.wrerr = err
+	rb.held = false
+	rb.duplex.release(rb)
 
 	// Signal all blocked readers and writers
 	rb.rdwait.Broadcast()
@@ -90,33 +278,153 @@ func (rb *ringBuff) closeWrite() error {
 	return nil
 }
 
+// waitDrained blocks till readers consumed every byte written to the
+// ring, the ring gets closed or ctx is done.
+func (rb *ringBuff) waitDrained(ctx context.Context) error {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	defer rb.watch(ctx, &rb.wrwait)()
+
+	for !rb.closed && rb.unread() > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rb.wait(&rb.wrwait, rb.wrid)
+	}
+	return nil
+}
+
+// reset discards the unread bytes and fails the reads and writes on the
+// ring with err from now on, like a TCP RST.
+func (rb *ringBuff) reset(err error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rb.buff = rb.buff[:0]
+	rb.r, rb.w = 0, 0
+	rb.prio = rb.prio[:0]
+	rb.dropped, rb.pending = len(rb.pending), nil
+	rb.transit, rb.intransit = nil, 0
+	rb.seq.reset()
+
+	rb.rsterr = err
+	rb.writeClosed = true
+	rb.wrerr = err
+	rb.held = false
+	rb.duplex.release(rb)
+
+	rb.rdwait.Broadcast()
+	rb.wrwait.Broadcast()
+}
+
+// watch wakes the waiters on c once ctx is done, until the returned
+// func is called.
+func (rb *ringBuff) watch(ctx context.Context, c *sync.Cond) func() {
+	done := ctx.Done()
+	if done == nil {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			rb.mu.Lock()
+			c.Broadcast()
+			rb.mu.Unlock()
+		case <-stop:
+		}
+	}()
+
+	return func() { close(stop) }
+}
+
+// wait blocks on c, or hands the turn to another conn when the ring is
+// driven by a scheduler. rb.mu must be held.
+func (rb *ringBuff) wait(c *sync.Cond, id int) {
+	n := &rb.wrwaiters
+	if c == &rb.rdwait {
+		n = &rb.rdwaiters
+	}
+	*n++
+	defer func() { *n-- }()
+
+	if rb.sched == nil {
+		c.Wait()
+		return
+	}
+
+	rb.mu.Unlock()
+	rb.sched.yield(id)
+	rb.mu.Lock()
+}
+
+// Write copies data into the ring. A write which fits into the ring's
+// capacity is atomic, like a pipe write of at most PIPE_BUF bytes: it
+// waits until there is room for all of it, so readers never observe a
+// part of it even if it fails on close or timeout. Bigger writes are
+// copied in as room frees up and return the partial count on failure.
 func (rb *ringBuff) Write(data []byte) (int, error) {
+	return rb.writeCtx(context.Background(), data)
+}
+
+// writeCtx is Write which also gives up waiting for room once ctx is done.
+func (rb *ringBuff) writeCtx(ctx context.Context, data []byte) (int, error) {
 	rb.wrwait.L.Lock()
 	defer rb.wrwait.L.Unlock()
+	defer rb.watch(ctx, &rb.wrwait)()
 
 	if rb.closed {
-		return 0, io.ErrClosedPipe
+		return 0, rb.errClosedPipe()
+	}
+
+	if cap(rb.buff) == 0 {
+		return rb.writeSync(ctx, data)
 	}
 
 	var n int
+	var turn func()
 
 	for len(data) > 0 {
+		need := len(data)
+		if need > cap(rb.buff) {
+			need = 1
+		}
+
 		// Wait until ringBuff drains
 		for {
 
 			if rb.closed || rb.writeClosed {
-				return 0, io.ErrClosedPipe
+				return n, rb.errClosedPipe()
 			}
 
-			if !rb.full() {
+			room := cap(rb.buff)-rb.buffered() >= need
+			if room && rb.duplex.mayWrite(rb) {
+				if rb.backpressure(false) {
+					continue
+				}
 				break
 			}
 
 			if rb.wrtimeout {
-				return 0, errTimeout
+				return n, errTimeout
+			}
+
+			if err := ctx.Err(); err != nil {
+				return n, err
 			}
 
-			rb.wrwait.Wait()
+			if !room && rb.backpressure(true) {
+				continue
+			}
+
+			rb.wait(&rb.wrwait, rb.wrid)
+		}
+
+		if turn == nil {
+			turn = rb.takeTurn()
+			defer turn()
 		}
 
 		endPos := cap(rb.buff)
@@ -125,6 +433,7 @@ func (rb *ringBuff) Write(data []byte) (int, error) {
 		}
 
 		cn := copy(rb.buff[rb.w:endPos], data)
+		rb.seq.wrote(seqMain, data[:cn])
 		n += cn
 		rb.w += cn
 
@@ -142,217 +451,1334 @@ func (rb *ringBuff) Write(data []byte) (int, error) {
 			rb.w = 0
 		}
 
-		if !rb.empty() {
-			// Ring buffer is not empty, signal readers
-			rb.rdwait.Signal()
+		if rb.metrics != nil {
+			rb.metrics.wrote(cn, rb.buffered())
 		}
+
+		rb.send(cn)
 	}
 
 	return n, nil
 }
 
-func (rb *ringBuff) Read(data []byte) (int, error) {
-	rb.rdwait.L.Lock()
-	defer rb.rdwait.L.Unlock()
+// writePrio queues data in the high priority lane, which holds up to the
+// ring's capacity and uses the same atomicity rules as Write.
+func (rb *ringBuff) writePrio(data []byte) (int, error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
 
-	for {
+	// There is nothing to skip ahead of without a buffer
+	if cap(rb.buff) == 0 {
+		return rb.writeSync(context.Background(), data)
+	}
 
-		if rb.closed {
-			return 0, io.ErrClosedPipe
-		}
+	if err := rb.pause(context.Background(), rb.transitDelay(len(data))); err != nil {
+		return 0, err
+	}
 
-		// Wait till ring buffer gets filled up
-		if !rb.empty() {
-			break
-		}
+	if rb.prio == nil {
+		rb.prio = make([]byte, 0, cap(rb.buff))
+	}
 
-		if rb.rdtimeout {
-			return 0, errTimeout
-		}
+	var n int
+	var turn func()
 
-		if rb.writeClosed {
-			return 0, io.EOF
+	for len(data) > 0 {
+		need := len(data)
+		if need > cap(rb.prio) {
+			need = 1
 		}
 
-		rb.rdwait.Wait()
-	}
+		for {
+			if rb.closed || rb.writeClosed {
+				return n, rb.errClosedPipe()
+			}
 
-	//reads are possible in window of [rb.r, len(rb.buff))
+			if cap(rb.prio)-len(rb.prio) >= need && rb.duplex.mayWrite(rb) {
+				break
+			}
 
-	n := copy(data, rb.buff[rb.r:len(rb.buff)])
-	rb.r += n
+			if rb.wrtimeout {
+				return n, errTimeout
+			}
 
-	if rb.r == cap(rb.buff) {
-		rb.r = 0
+			rb.wait(&rb.wrwait, rb.wrid)
+		}
 
-		// Adjust the reading window ring buffer
-		// new window should be [0, rb.w)
-		rb.buff = rb.buff[:rb.w]
-	}
+		if turn == nil {
+			turn = rb.takeTurn()
+			defer turn()
+		}
 
-	if !rb.full() {
-		// Ring buffer is not full, signal writers
-		rb.wrwait.Signal()
+		cn := len(data)
+		if room := cap(rb.prio) - len(rb.prio); cn > room {
+			cn = room
+		}
+
+		rb.prio = append(rb.prio, data[:cn]...)
+		rb.seq.wrote(seqPrio, data[:cn])
+		if rb.metrics != nil {
+			rb.metrics.wrote(cn, rb.buffered())
+		}
+		rb.watchdogWrote()
+		data = data[cn:]
+		n += cn
+
+		// High priority bytes skip the coalescing
+		rb.rdwait.Broadcast()
 	}
 
 	return n, nil
 }
 
-func newRingBuff(size int) *ringBuff {
-	b := make([]byte, 0, size)
+// deliver makes the buffered bytes readable, right away unless writes
+// are being coalesced. rb.mu must be held.
+func (rb *ringBuff) deliver() {
+	if rb.delay <= 0 || rb.buffered() == cap(rb.buff) {
+		rb.flush()
+		return
+	}
 
-	rb := &ringBuff{}
-	rb.buff = b
-	rb.rdwait.L = &rb.mu
-	rb.wrwait.L = &rb.mu
-	rb.rdtimer = time.AfterFunc(0, func() {})
-	rb.wrtimer = time.AfterFunc(0, func() {})
-	return rb
-}
+	if !rb.held {
+		rb.held = true
 
-type conn struct {
-	r io.Reader
-	w io.Writer
+		var tm stopper
+		tm = rb.clock.AfterFunc(rb.delay, func() {
+			rb.mu.Lock()
+			defer rb.mu.Unlock()
+
+			// The batch was flushed while the timer fired, the
+			// held bytes are a later one's
+			if !rb.held || rb.delaytimer != tm {
+				return
+			}
+			rb.flush()
+		})
+		rb.delaytimer = tm
+	}
 }
 
-func (c *conn) LocalAddr() net.Addr {
-	return addr{}
+// flush releases held bytes to readers. rb.mu must be held.
+func (rb *ringBuff) flush() {
+	if rb.held {
+		rb.held = false
+		rb.delaytimer.Stop()
+	}
+
+	// Wake all the readers: the one a Signal picks may take less than
+	// was written, or wait for its turn, and leave the others asleep
+	rb.rdwait.Broadcast()
 }
 
-func (c *conn) RemoteAddr() net.Addr {
-	return addr{}
+func (rb *ringBuff) Read(data []byte) (int, error) {
+	return rb.readCtx(context.Background(), data)
 }
 
-func (c *conn) SetReadDeadline(t time.Time) error {
-	rb := c.r.(*ringBuff)
-	rb.mu.Lock()
-	defer rb.mu.Unlock()
+// readCtx is Read which also gives up waiting for data once ctx is done.
+func (rb *ringBuff) readCtx(ctx context.Context, data []byte) (int, error) {
+	rb.rdwait.L.Lock()
+	defer rb.rdwait.L.Unlock()
+	defer rb.watch(ctx, &rb.rdwait)()
 
-	rb.rdtimer.Stop()
+	if rb.fairness > 0 {
+		leave, err := rb.awaitTurn(ctx)
+		if err != nil {
+			return 0, err
+		}
+		defer leave()
 
-	// If t is not initiliazed
-	if t.IsZero() {
-		rb.rdtimeout = false
-		return nil
+		if len(data) > rb.fairness {
+			data = data[:rb.fairness]
+		}
 	}
 
-	rb.rdtimer = time.AfterFunc(time.Until(t), func() {
-		rb.mu.Lock()
-		defer rb.mu.Unlock()
-		rb.rdtimeout = true
-		rb.rdwait.Broadcast()
-	})
+	if err := rb.awaitReadable(ctx); err != nil {
+		return 0, err
+	}
 
-	return nil
+	return rb.take(data), nil
 }
 
-func (c *conn) SetWriteDeadline(t time.Time) error {
-	rb := c.r.(*ringBuff)
+// readMulti is readCtx filling bufs one after the other, with as many
+// bytes as are readable at once, like readv.
+func (rb *ringBuff) readMulti(ctx context.Context, bufs [][]byte) (int, error) {
 	rb.mu.Lock()
 	defer rb.mu.Unlock()
+	defer rb.watch(ctx, &rb.rdwait)()
 
-	rb.wrtimer.Stop()
+	if err := rb.awaitReadable(ctx); err != nil {
+		return 0, err
+	}
 
-	// If t is not initialized
-	if t.IsZero() {
-		rb.wrtimeout = false
-		return nil
+	var n int
+	for _, b := range bufs {
+		for len(b) > 0 && rb.readable() {
+			cn := rb.take(b)
+			b = b[cn:]
+			n += cn
+		}
 	}
+	return n, nil
+}
 
-	rb.wrtimer = time.AfterFunc(time.Until(t), func() {
-		rb.mu.Lock()
-		defer rb.mu.Unlock()
+// awaitReadable waits till the ring is readable and returns the error a
+// read fails with otherwise. rb.mu must be held.
+func (rb *ringBuff) awaitReadable(ctx context.Context) error {
+	for {
 
-		rb.wrtimeout = true
-		rb.wrwait.Broadcast()
-	})
+		if rb.closed {
+			return rb.errClosedPipe()
+		}
 
-	return nil
-}
+		// Wait till ring buffer gets filled up
+		if rb.readable() {
+			return nil
+		}
 
-func (c *conn) SetDeadline(t time.Time) error {
-	c.SetReadDeadline(t)
-	c.SetWriteDeadline(t)
-	return nil
-}
+		// Only time out a read which has nothing to return,
+		// bytes buffered before the deadline are still delivered
+		if rb.rdtimeout {
+			return errTimeout
+		}
 
-func (c *conn) Read(b []byte) (int, error) {
-	return c.r.Read(b)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if rb.writeClosed && !rb.finHeld && rb.intransit == 0 {
+			if rb.wrerr != nil {
+				return rb.wrerr
+			}
+			return rb.errEOF()
+		}
+
+		if rb.nonblock {
+			return errWouldBlock
+		}
+
+		rb.wait(&rb.rdwait, rb.rdid)
+	}
 }
 
-func (c *conn) Write(b []byte) (int, error) {
-	return c.w.Write(b)
+// take copies the readable bytes into data, high priority ones first,
+// and wakes up the writers waiting for room. rb.mu must be held and the
+// ring readable.
+func (rb *ringBuff) take(data []byte) int {
+	return rb.consume(data, len(data))
 }
 
-func (c *conn) Close() error {
-	err := c.r.(*ringBuff).Close()
-	if err != nil {
-		return fmt.Errorf("closing a closed connection")
+// chunk returns the readable bytes which take copies from next. rb.mu
+// must be held and the ring readable.
+func (rb *ringBuff) chunk() []byte {
+	if len(rb.prio) > 0 {
+		return rb.prio
 	}
-	err = c.w.(*ringBuff).closeWrite()
-	if err != nil {
-		return fmt.Errorf("closing a closed connection")
+
+	if len(rb.pending) > 0 {
+		return rb.pending
 	}
-	return nil
-}
 
-// Listener satisfies net.Listener
-type Listener struct {
-	mu     sync.Mutex
-	bsz    int
-	connCh chan net.Conn
-	done   chan struct{}
-	addr   net.Addr
+	chunk := rb.buff[rb.r:len(rb.buff)]
+	if arrived := rb.buffered() - rb.intransit; len(chunk) > arrived {
+		chunk = chunk[:arrived]
+	}
+	return chunk
 }
 
-func (l *Listener) Close() error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+// consume is take of up to n bytes which are copied into data, or just
+// skipped over if data is nil.
+func (rb *ringBuff) consume(data []byte, n int) int {
+	if rb.wd != nil {
+		defer rb.watchdogRead()
+	}
 
-	select {
-	case <-l.done:
-		return io.ErrClosedPipe
-	default:
-		close(l.done)
+	chunk := rb.chunk()
+	if n > len(chunk) {
+		n = len(chunk)
 	}
-	return nil
-}
 
-func (l *Listener) Accept() (net.Conn, error) {
-	select {
-	case <-l.done:
-		return nil, io.ErrClosedPipe
-	case c := <-l.connCh:
-		return c, nil
+	if data != nil {
+		copy(data, chunk[:n])
 	}
-}
 
-func (l *Listener) Addr() net.Addr { return l.addr }
+	if len(rb.prio) > 0 {
+		rb.seq.read(seqPrio, chunk[:n])
+		rb.prio = rb.prio[:copy(rb.prio, rb.prio[n:])]
+		rb.drained()
 
-// Dial returns a client side connection to the attached to thre reciever.
-func (l *Listener) Dial() (net.Conn, error) {
-	select {
-	case <-l.done:
-		return nil, io.ErrClosedPipe
-	default:
-		p1 := newRingBuff(l.bsz)
-		p2 := newRingBuff(l.bsz)
+		// High priority lane has room, signal writers
+		rb.wrwait.Broadcast()
+		return n
+	}
+
+	if len(rb.pending) > 0 {
+		rb.pending = rb.pending[n:]
+		rb.drained()
 
-		l.connCh <- &conn{p1, p2}
-		return &conn{p2, p1}, nil
+		// Signal the writer waiting for its write to be consumed
+		rb.wrwait.Broadcast()
+		return n
 	}
-}
 
-// Listen returns a *Listener which can queue connQSize number of
-// new connections till it blocks the call to Accept() and have
-//transport buffer size of transBuffSize
-func Listen(connQSize, transBuffSize int, _addr string) (*Listener, error) {
-	l := &Listener{
-		sync.Mutex{},
-		transBuffSize,
-		make(chan net.Conn, connQSize),
-		make(chan struct{}),
-		addr{_addr},
+	//reads are possible in window of [rb.r, len(rb.buff))
+
+	rb.seq.read(seqMain, chunk[:n])
+	rb.r += n
+
+	if rb.r == cap(rb.buff) {
+		rb.r = 0
+
+		// Adjust the reading window ring buffer
+		// new window should be [0, rb.w)
+		rb.buff = rb.buff[:rb.w]
+	}
+
+	rb.drained()
+
+	if !rb.full() {
+		// Ring buffer is not full, signal writers. They wait for
+		// different amounts of room, so wake them all.
+		rb.wrwait.Broadcast()
+	}
+
+	return n
+}
+
+// waitReadable blocks like readCtx till a Read can return right away,
+// without consuming anything.
+func (rb *ringBuff) waitReadable(ctx context.Context) error {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	defer rb.watch(ctx, &rb.rdwait)()
+
+	return rb.awaitReadable(ctx)
+}
+
+// setDeadline replaces the deadline kept in timer and timeout, waking
+// the waiters on c once t passes. A zero t clears the deadline, even for
+// an operation which is already blocked. rb.mu must be held.
+func (rb *ringBuff) setDeadline(timer **time.Timer, timeout *bool, c *sync.Cond, t time.Time) {
+	if *timer != nil {
+		(*timer).Stop()
+		*timer = nil
+	}
+	*timeout = false
+
+	// If t is not initialized
+	if t.IsZero() {
+		return
+	}
+
+	d := time.Until(t)
+	if d <= 0 {
+		*timeout = true
+		c.Broadcast()
+		return
+	}
+
+	var tm *time.Timer
+	tm = time.AfterFunc(d, func() {
+		rb.mu.Lock()
+		defer rb.mu.Unlock()
+
+		// The deadline was changed while the timer fired
+		if *timer != tm {
+			return
+		}
+
+		*timeout = true
+		c.Broadcast()
+	})
+	*timer = tm
+}
+
+func newRingBuff(size int) *ringBuff {
+	b := make([]byte, 0, size)
+
+	rb := &ringBuff{}
+	rb.buff = b
+	rb.mu = &sync.Mutex{}
+	rb.clock = realClock{}
+	rb.rdwait.L = rb.mu
+	rb.wrwait.L = rb.mu
+	return rb
+}
+
+// pair is the state shared by both ends of a connection.
+type pair struct {
+	// latency is accessed atomically and kept first for alignment
+	latency int64
+
+	closeOnce sync.Once
+	ln        *Listener
+	dialed    time.Time
+
+	// closeFns run once the pair is closed, see onClose, and notify
+	// is closed then, see CloseNotify
+	mu       sync.Mutex
+	closeFns []func()
+	notify   chan struct{}
+	isClosed bool
+
+	// id tells the pairs apart, it is unique in the process
+	id uint64
+
+	transcript *transcript
+	meta       Meta
+}
+
+// pairIDs hands out the ids of the pairs, it is accessed atomically.
+var pairIDs uint64
+
+// accepted records how long the conn waited in the accept queue.
+func (p *pair) accepted() {
+	atomic.StoreInt64(&p.latency, int64(time.Since(p.dialed)))
+}
+
+// Stats are the statistics of one end of a conn.
+type Stats struct {
+	// ConnectLatency is the time between the Dial and the Accept
+	// which paired up the conn, it is the same for both ends
+	ConnectLatency time.Duration
+
+	// BytesWritten and BytesRead count the bytes as they went over
+	// the wire, that is after compression
+	BytesWritten int64
+	BytesRead    int64
+
+	// ReadLatency and WriteLatency are nil unless the conn was
+	// dialed WithLatencyHistogram
+	ReadLatency  *LatencyHistogram
+	WriteLatency *LatencyHistogram
+}
+
+// closed runs once, when the first of the two ends is closed.
+func (p *pair) closed() {
+	p.closeOnce.Do(func() {
+		atomic.AddInt64(&p.ln.nconns, -1)
+
+		p.mu.Lock()
+		fns := p.closeFns
+		p.closeFns, p.isClosed = nil, true
+		if p.notify != nil {
+			close(p.notify)
+		}
+		p.mu.Unlock()
+
+		for _, f := range fns {
+			f()
+		}
+	})
+}
+
+// onClose makes f run once the pair is closed, right away if it is
+// closed already.
+func (p *pair) onClose(f func()) {
+	p.mu.Lock()
+	if !p.isClosed {
+		p.closeFns = append(p.closeFns, f)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+
+	f()
+}
+
+type conn struct {
+	// nwritten and nread are accessed atomically and kept first
+	// for alignment
+	nwritten int64
+	nread    int64
+
+	r io.Reader
+	w io.Writer
+
+	laddr, raddr net.Addr
+
+	pair *pair
+
+	// dialer is set on the dialing end
+	dialer bool
+
+	// mtu caps the bytes per Read, see WithMTU
+	mtu int
+
+	// rdhist and wrhist time the Reads and Writes
+	rdhist, wrhist *histogram
+
+	sched *scheduler
+	id    int
+
+	mu        sync.Mutex
+	tees      []*ringBuff
+	lifetimer *time.Timer
+	closed    bool
+
+	// quota is what is left of the write quota when metered is set
+	quota   int64
+	metered bool
+
+	// rdlock and wrlock are the advisory locks of LockRead and LockWrite
+	rdlock sync.Mutex
+	wrlock sync.Mutex
+
+	gz *compressor
+}
+
+// Cap returns the capacity of the buffer the conn reads from.
+func (c *conn) Cap() int {
+	return cap(c.r.(*ringBuff).buff)
+}
+
+// Buffered returns the number of bytes sent by the peer which the conn
+// hasn't read yet. Compressed conns count the bytes still in the buffer
+// compressed.
+func (c *conn) Buffered() int {
+	rb := c.r.(*ringBuff)
+	rb.mu.Lock()
+	n := rb.unread()
+	rb.mu.Unlock()
+
+	if c.gz != nil {
+		n += c.gz.buffered()
+	}
+	return n
+}
+
+// InFlight returns the number of bytes written by either end of the
+// pair which the other end hasn't read yet, the sum of both directions,
+// so it is the same on the two ends. Unlike Buffered, compressed bytes
+// are only counted while they are in the buffers of the pair.
+func (c *conn) InFlight() int {
+	n := 0
+	for _, rb := range []*ringBuff{c.r.(*ringBuff), c.w.(*ringBuff)} {
+		rb.mu.Lock()
+		n += rb.unread()
+		rb.mu.Unlock()
+	}
+	return n
+}
+
+// Stats returns a snapshot of the conn's statistics.
+func (c *conn) Stats() Stats {
+	return Stats{
+		ConnectLatency: time.Duration(atomic.LoadInt64(&c.pair.latency)),
+		BytesWritten:   atomic.LoadInt64(&c.nwritten),
+		BytesRead:      atomic.LoadInt64(&c.nread),
+		ReadLatency:    c.rdhist.snapshot(),
+		WriteLatency:   c.wrhist.snapshot(),
+	}
+}
+
+// ResetStats zeroes the byte counts and latency histograms of the conn,
+// so the Stats of a long test can be taken per phase. The ConnectLatency
+// is kept. Bytes moved by concurrent Reads and Writes count either
+// before or after the reset, never twice.
+func (c *conn) ResetStats() {
+	atomic.StoreInt64(&c.nwritten, 0)
+	atomic.StoreInt64(&c.nread, 0)
+	c.rdhist.reset()
+	c.wrhist.reset()
+}
+
+func (c *conn) LocalAddr() net.Addr {
+	return c.laddr
+}
+
+func (c *conn) RemoteAddr() net.Addr {
+	return c.raddr
+}
+
+func (c *conn) SetReadDeadline(t time.Time) error {
+	rb := c.r.(*ringBuff)
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rb.setDeadline(&rb.rdtimer, &rb.rdtimeout, &rb.rdwait, t)
+	return nil
+}
+
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	rb := c.w.(*ringBuff)
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rb.setDeadline(&rb.wrtimer, &rb.wrtimeout, &rb.wrwait, t)
+	return nil
+}
+
+// SetNoDelay controls whether written bytes are delivered to the peer
+// as soon as possible, which is the default. With noDelay false small
+// writes are coalesced and the peer is only woken once they fill up the
+// buffer or after a short delay, like Nagle's algorithm.
+func (c *conn) SetNoDelay(noDelay bool) error {
+	if noDelay {
+		return c.SetWriteCoalesce(0)
+	}
+	return c.SetWriteCoalesce(coalesceDelay)
+}
+
+// SetWriteCoalesce is SetNoDelay with a precise delay: written bytes are
+// held back from the peer for up to d after the first write of a batch,
+// unless they fill up the buffer first. A d <= 0 delivers writes right
+// away.
+func (c *conn) SetWriteCoalesce(d time.Duration) error {
+	rb := c.w.(*ringBuff)
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rb.delay = d
+	if d <= 0 {
+		rb.flush()
+	}
+	return nil
+}
+
+// SetMaxLifetime ends the conn once d elapsed: both ends then read io.EOF
+// after draining what is buffered and their writes fail with
+// io.ErrClosedPipe. A d <= 0 cancels a previously set lifetime.
+func (c *conn) SetMaxLifetime(d time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lifetimer != nil {
+		c.lifetimer.Stop()
+		c.lifetimer = nil
+	}
+
+	if d > 0 {
+		c.lifetimer = time.AfterFunc(d, c.expire)
+	}
+	return nil
+}
+
+func (c *conn) expire() {
+	c.w.(*ringBuff).closeWrite(nil)
+	c.r.(*ringBuff).closeWrite(nil)
+	c.pair.closed()
+}
+
+func (c *conn) SetDeadline(t time.Time) error {
+	c.SetReadDeadline(t)
+	c.SetWriteDeadline(t)
+	return nil
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	return c.readCtx(context.Background(), b)
+}
+
+// readCtx is Read which also gives up waiting for data once ctx is done.
+func (c *conn) readCtx(ctx context.Context, b []byte) (int, error) {
+	if c.sched != nil {
+		c.sched.acquire(c.id)
+		defer c.sched.release(c.id)
+	}
+
+	if c.rdhist != nil {
+		defer c.rdhist.since(time.Now())
+	}
+
+	b = c.segment(b)
+
+	var n int
+	var err error
+
+	if c.gz != nil {
+		n, err = c.gz.read(ctx, c, b)
+	} else {
+		n, err = c.r.(*ringBuff).readCtx(ctx, b)
+		atomic.AddInt64(&c.nread, int64(n))
+	}
+
+	c.pair.transcript.record(c.dialer, false, b[:n])
+
+	// Timeouts, canceled and would-block reads don't end the stream
+	if n > 0 || (err != nil && err != errTimeout && err != errWouldBlock && err != ctx.Err()) {
+		c.tee(b[:n], err)
+	}
+	return n, err
+}
+
+func (c *conn) Write(b []byte) (int, error) {
+	return c.writeCtx(context.Background(), b)
+}
+
+// writeCtx is Write which also gives up waiting for room once ctx is done.
+func (c *conn) writeCtx(ctx context.Context, b []byte) (int, error) {
+	if c.wrhist != nil {
+		defer c.wrhist.since(time.Now())
+	}
+
+	b, qerr := c.reserve(b)
+	if len(b) == 0 && qerr != nil {
+		return 0, qerr
+	}
+
+	if c.sched != nil {
+		c.sched.acquire(c.id)
+		defer c.sched.release(c.id)
+	}
+
+	var n int
+	var err error
+
+	if c.gz != nil {
+		n, err = c.gz.write(ctx, c, b)
+	} else {
+		n, err = c.w.(*ringBuff).writeCtx(ctx, b)
+		atomic.AddInt64(&c.nwritten, int64(n))
+	}
+
+	c.pair.transcript.record(c.dialer, true, b[:n])
+
+	c.refund(len(b) - n)
+	if err == nil {
+		err = qerr
+	}
+	return n, err
+}
+
+// WritePriority writes p like Write when high is false. High priority
+// bytes skip ahead of all the normal bytes queued for the peer, which is
+// handy to simulate out of band or QoS traffic.
+func (c *conn) WritePriority(p []byte, high bool) (int, error) {
+	// Compressed frames must not be split by high priority ones
+	if !high || c.gz != nil {
+		return c.Write(p)
+	}
+
+	if c.wrhist != nil {
+		defer c.wrhist.since(time.Now())
+	}
+
+	p, qerr := c.reserve(p)
+	if len(p) == 0 && qerr != nil {
+		return 0, qerr
+	}
+
+	if c.sched != nil {
+		c.sched.acquire(c.id)
+		defer c.sched.release(c.id)
+	}
+
+	n, err := c.w.(*ringBuff).writePrio(p)
+	atomic.AddInt64(&c.nwritten, int64(n))
+	c.pair.transcript.record(c.dialer, true, p[:n])
+
+	c.refund(len(p) - n)
+	if err == nil {
+		err = qerr
+	}
+	return n, err
+}
+
+// WaitReadable blocks till at least one byte can be read from the conn,
+// without consuming it, so a Read then doesn't block. It fails with the
+// error such a Read would fail with, like io.EOF once the peer closed
+// and everything was read, or with the ctx error once ctx is done.
+func (c *conn) WaitReadable(ctx context.Context) error {
+	if c.sched != nil {
+		c.sched.acquire(c.id)
+		defer c.sched.release(c.id)
+	}
+
+	return c.r.(*ringBuff).waitReadable(ctx)
+}
+
+// ReadMulti reads into bufs in order, like readv: it blocks like Read
+// till there is something to read and then fills the buffers with as
+// much as is readable, in a single operation no other reader can
+// interleave with. It returns the total number of bytes read.
+func (c *conn) ReadMulti(bufs [][]byte) (int, error) {
+	if c.gz != nil {
+		return c.readMultiCopy(bufs)
+	}
+
+	if c.sched != nil {
+		c.sched.acquire(c.id)
+		defer c.sched.release(c.id)
+	}
+
+	n, err := c.r.(*ringBuff).readMulti(context.Background(), bufs)
+	atomic.AddInt64(&c.nread, int64(n))
+
+	if n > 0 || (err != nil && err != errTimeout && err != errWouldBlock) {
+		left := n
+		for _, b := range bufs {
+			if len(b) > left {
+				b = b[:left]
+			}
+			left -= len(b)
+			c.pair.transcript.record(c.dialer, false, b)
+			c.tee(b, nil)
+		}
+		if err != nil {
+			c.tee(nil, err)
+		}
+	}
+	return n, err
+}
+
+// readMultiCopy is ReadMulti for the conns which have to decode what
+// they read, it reads once and scatters the result over bufs.
+func (c *conn) readMultiCopy(bufs [][]byte) (int, error) {
+	var size int
+	for _, b := range bufs {
+		size += len(b)
+	}
+
+	p := make([]byte, size)
+	n, err := c.Read(p)
+
+	p = p[:n]
+	for _, b := range bufs {
+		p = p[copy(b, p):]
+	}
+	return n, err
+}
+
+// copyBufSize is the size of the buffer ReadFrom and WriteTo copy through.
+const copyBufSize = 32 << 10
+
+// ReadFrom writes everything read from r to the conn, till r returns
+// io.EOF. It lets io.Copy skip its intermediate buffer. The write
+// deadline covers the whole copy: once it passes, ReadFrom returns the
+// bytes written so far and the timeout error.
+func (c *conn) ReadFrom(r io.Reader) (int64, error) {
+	buf := make([]byte, copyBufSize)
+	var n int64
+
+	for {
+		rn, rerr := r.Read(buf)
+		if rn > 0 {
+			wn, werr := c.Write(buf[:rn])
+			n += int64(wn)
+			if werr != nil {
+				return n, werr
+			}
+		}
+
+		if rerr == io.EOF {
+			return n, nil
+		}
+		if rerr != nil {
+			return n, rerr
+		}
+	}
+}
+
+// WriteTo writes everything read from the conn to w, till the peer
+// closes. It lets io.Copy skip its intermediate buffer. Like ReadFrom,
+// it stops with the timeout error once the read deadline passes.
+func (c *conn) WriteTo(w io.Writer) (int64, error) {
+	buf := make([]byte, copyBufSize)
+	var n int64
+
+	for {
+		rn, rerr := c.Read(buf)
+		if rn > 0 {
+			wn, werr := w.Write(buf[:rn])
+			n += int64(wn)
+			if werr != nil {
+				return n, werr
+			}
+			if wn != rn {
+				return n, io.ErrShortWrite
+			}
+		}
+
+		if rerr == io.EOF {
+			return n, nil
+		}
+		if rerr != nil {
+			return n, rerr
+		}
+	}
+}
+
+func (c *conn) WriteString(s string) (int, error) {
+	return c.Write([]byte(s))
+}
+
+func (c *conn) Close() error {
+	return c.CloseWithError(nil)
+}
+
+// CloseWithError closes the conn like Close, but the peer reads err
+// instead of io.EOF once it has drained the buffered data. A nil err
+// behaves exactly like Close.
+func (c *conn) CloseWithError(err error) error {
+	_, err = c.close(err)
+	return err
+}
+
+// Abort closes the conn like a TCP RST instead of a graceful close: the
+// bytes still buffered in both directions are discarded and the peer's
+// reads and writes fail with a connection reset net.Error, which matches
+// syscall.ECONNRESET, rather than io.EOF.
+func (c *conn) Abort() error {
+	return c.CloseWithError(errReset)
+}
+
+// CloseWithStats closes the conn like Close and reports how many bytes
+// sent by the peer were still unread and got discarded.
+func (c *conn) CloseWithStats() (unread int64, err error) {
+	n, err := c.close(nil)
+	return int64(n), err
+}
+
+// CloseRead shuts down the reading side of the conn: the unread bytes
+// are discarded, Reads fail and so do the peer's Writes, with
+// io.ErrClosedPipe. This end can still write to the peer.
+func (c *conn) CloseRead() error {
+	_, err := c.r.(*ringBuff).close()
+	return err
+}
+
+// CloseWrite shuts down the writing side of the conn: the peer reads
+// io.EOF once it drained the buffered data, while this end can still
+// read what the peer sends.
+func (c *conn) CloseWrite() error {
+	return c.w.(*ringBuff).closeWrite(nil)
+}
+
+// PeerClosedWrite reports whether the peer is done sending, because it
+// called CloseWrite or closed the conn. Reads then return io.EOF, or the
+// error the peer closed with, once the buffered bytes were read.
+func (c *conn) PeerClosedWrite() bool {
+	rb := c.r.(*ringBuff)
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	return rb.writeClosed && !rb.finHeld
+}
+
+// CloseAfterDrain shuts down the writing side like CloseWrite, waits till
+// the peer has read all of the buffered bytes and then closes the conn.
+// The conn is closed even when ctx is done first, the ctx error is
+// returned in that case.
+func (c *conn) CloseAfterDrain(ctx context.Context) error {
+	if err := c.CloseWrite(); err != nil {
+		return err
+	}
+
+	if c.sched != nil {
+		c.sched.acquire(c.id)
+		defer c.sched.release(c.id)
+	}
+
+	werr := c.w.(*ringBuff).waitDrained(ctx)
+
+	if err := c.Close(); err != nil {
+		return err
+	}
+	return werr
+}
+
+func (c *conn) close(err error) (int, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, fmt.Errorf("closing a closed connection")
+	}
+	c.closed = true
+
+	if c.lifetimer != nil {
+		c.lifetimer.Stop()
+	}
+	c.mu.Unlock()
+
+	if c.sched != nil {
+		c.sched.unregister(c.id)
+	}
+
+	// An abortive close drops what is buffered in both directions
+	if err == errReset {
+		c.r.(*ringBuff).reset(err)
+		c.w.(*ringBuff).reset(err)
+	}
+
+	// The reading side may have been shut down by CloseRead already
+	unread, _ := c.r.(*ringBuff).close()
+
+	// The peer may have closed its reading side already
+	if err != errReset {
+		c.w.(*ringBuff).holdFin()
+	}
+	c.w.(*ringBuff).closeWrite(err)
+
+	c.tee(nil, io.EOF)
+
+	c.pair.closed()
+	return unread, nil
+}
+
+// Listener satisfies net.Listener
+type Listener struct {
+	// nconns is accessed atomically and kept first for alignment
+	nconns int64
+
+	// accepting is the number of goroutines blocked in Accept
+	accepting int32
+
+	mu     sync.Mutex
+	bsz    int
+	connCh chan net.Conn
+	done   chan struct{}
+	addr   net.Addr
+
+	// rewire is closed when Reconfigure replaces connCh
+	rewire chan struct{}
+
+	sched   *scheduler
+	metrics *metrics
+
+	// resume is closed by ResumeAccept, it is nil unless paused
+	resume       chan struct{}
+	rejectPaused bool
+
+	halfDuplex bool
+	closedErr  error
+	eofErr     error
+	clock      clock
+
+	// refuse fails all dials when set
+	refuse error
+
+	// maxAccepts is the number of accepts after which the listener
+	// closes, naccepts is accessed atomically
+	maxAccepts int32
+	naccepts   int32
+
+	// maxConns caps nconns, see WithMaxConns
+	maxConns int64
+
+	// labeled holds the accept queues of the labeled dials
+	labeled map[string]chan net.Conn
+
+	// transcripts are the records of WithTranscript by pair id,
+	// transcriptIDs the ids in the order the pairs were dialed
+	transcripts   map[uint64]*transcript
+	transcriptIDs []uint64
+
+	mtu          int
+	readFairness int
+	writerQueue  int
+
+	watchdogD time.Duration
+	onStall   func(connID uint64)
+
+	handshake func(local, remote net.Conn) error
+}
+
+// Option configures a Listener at creation time.
+type Option func(*Listener)
+
+func (l *Listener) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-l.done:
+		return io.ErrClosedPipe
+	default:
+		close(l.done)
+	}
+	return nil
+}
+
+func (l *Listener) isClosed() bool {
+	select {
+	case <-l.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	return l.accept("")
+}
+
+// accept waits for the next conn queued for label.
+func (l *Listener) accept(label string) (net.Conn, error) {
+	connCh, rewire := l.queue(label)
+
+	// Only count the call as pending if no conn is queued already
+	select {
+	case c := <-connCh:
+		return l.accepted(c)
+	default:
+	}
+
+	atomic.AddInt32(&l.accepting, 1)
+	defer atomic.AddInt32(&l.accepting, -1)
+
+	for {
+		select {
+		case <-l.done:
+			return nil, io.ErrClosedPipe
+		case c := <-connCh:
+			return l.accepted(c)
+		case <-rewire:
+			// Reconfigure replaced the queue
+			connCh, rewire = l.queue(label)
+		}
+	}
+}
+
+// accepted hands out c taken off an accept queue.
+func (l *Listener) accepted(c net.Conn) (net.Conn, error) {
+	if !l.countAccept() {
+		c.Close()
+		return nil, io.ErrClosedPipe
+	}
+
+	c.(*conn).pair.accepted()
+	return c, nil
+}
+
+func (l *Listener) Addr() net.Addr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.addr
+}
+
+// Rebind changes the address the listener reports. Conns dialed from now
+// on use it, existing conns keep the address they were dialed with.
+func (l *Listener) Rebind(_addr string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.addr = addr{_addr}
+}
+
+// HasPendingAccept reports whether a goroutine is blocked in Accept
+// waiting for a conn to be dialed.
+func (l *Listener) HasPendingAccept() bool {
+	return atomic.LoadInt32(&l.accepting) > 0
+}
+
+// Dial returns a client side connection to the attached to thre reciever.
+func (l *Listener) Dial() (net.Conn, error) {
+	return l.DialContext(context.Background())
+}
+
+// DialOption overrides the listener defaults for a single connection.
+type DialOption func(*dialOptions)
+
+type dialOptions struct {
+	rdsz int
+	wrsz int
+
+	// laddr is the address the dialed conn reports as its own
+	laddr net.Addr
+
+	ctxDeadline bool
+
+	latency   time.Duration
+	jitter    *jitter
+	bandwidth int64
+	compress  bool
+	seqCheck  bool
+	histogram bool
+	nonblock  bool
+
+	closeDelay time.Duration
+
+	// quota caps the bytes written by each end when metered is set
+	quota   int64
+	metered bool
+
+	label string
+	meta  Meta
+}
+
+// WithReadBufferSize sets the size of the buffer the dialed conn reads
+// from, which is the buffer the accepted peer writes into.
+func WithReadBufferSize(size int) DialOption {
+	return func(o *dialOptions) { o.rdsz = size }
+}
+
+// WithBufferSize sets the size of the buffers of both directions. A size
+// of zero makes the conn unbuffered: each Write blocks until the peer's
+// Reads consumed all of it, like io.Pipe and net.Pipe.
+func WithBufferSize(size int) DialOption {
+	return func(o *dialOptions) { o.rdsz, o.wrsz = size, size }
+}
+
+// WithWriteBufferSize sets the size of the buffer the dialed conn writes
+// into, which is the buffer the accepted peer reads from.
+func WithWriteBufferSize(size int) DialOption {
+	return func(o *dialOptions) { o.wrsz = size }
+}
+
+// WithContextDeadline makes the dialed conn start out with the deadline
+// of the context passed to DialContext as its read and write deadline.
+func WithContextDeadline() DialOption {
+	return func(o *dialOptions) { o.ctxDeadline = true }
+}
+
+// DialContext is like Dial but gives up waiting for a free slot in the
+// accept queue once ctx is done.
+func (l *Listener) DialContext(ctx context.Context, opts ...DialOption) (net.Conn, error) {
+	l.mu.Lock()
+	bsz := l.bsz
+	l.mu.Unlock()
+
+	o := dialOptions{rdsz: bsz, wrsz: bsz, laddr: addr{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.rdsz < 0 || o.wrsz < 0 {
+		return nil, fmt.Errorf("invalid buffer size")
+	}
+
+	select {
+	case <-l.done:
+		return nil, io.ErrClosedPipe
+	default:
+	}
+
+	if l.refuse != nil {
+		return nil, l.refuse
+	}
+
+	if err := l.waitResumed(ctx); err != nil {
+		return nil, err
+	}
+
+	p1 := newRingBuff(o.wrsz)
+	p2 := newRingBuff(o.rdsz)
+
+	if l.halfDuplex {
+		newDuplex(p1, p2)
+	}
+
+	pr := &pair{ln: l, dialed: time.Now(), id: atomic.AddUint64(&pairIDs, 1)}
+	pr.transcript = l.newTranscript(pr.id)
+	pr.meta = o.meta
+	p1.metrics, p2.metrics = l.metrics, l.metrics
+	p1.latency, p2.latency = o.latency, o.latency
+	p1.jitter, p2.jitter = o.jitter, o.jitter
+	p1.bandwidth, p2.bandwidth = o.bandwidth, o.bandwidth
+	p1.closeDelay, p2.closeDelay = o.closeDelay, o.closeDelay
+	if o.seqCheck {
+		p1.seq, p2.seq = &seqCheck{}, &seqCheck{}
+	}
+	p1.closedErr, p2.closedErr = l.closedErr, l.closedErr
+	p1.eofErr, p2.eofErr = l.eofErr, l.eofErr
+	p1.clock, p2.clock = l.clock, l.clock
+	p1.fairness, p2.fairness = l.readFairness, l.readFairness
+	p1.wrqueue, p2.wrqueue = l.writerQueue, l.writerQueue
+	p1.nonblock, p2.nonblock = o.nonblock, o.nonblock
+	if l.onStall != nil {
+		p1.wd = &watchdog{d: l.watchdogD, onStall: l.onStall, id: pr.id}
+		p2.wd = &watchdog{d: l.watchdogD, onStall: l.onStall, id: pr.id}
+	}
+
+	laddr := l.Addr()
+	local := &conn{r: p2, w: p1, laddr: o.laddr, raddr: laddr, pair: pr, dialer: true}
+	remote := &conn{r: p1, w: p2, laddr: laddr, raddr: o.laddr, pair: pr}
+
+	if o.compress {
+		local.gz, remote.gz = newCompressor(), newCompressor()
+	}
+
+	local.mtu, remote.mtu = l.mtu, l.mtu
+
+	if o.histogram {
+		local.rdhist, local.wrhist = newHistogram(), newHistogram()
+		remote.rdhist, remote.wrhist = newHistogram(), newHistogram()
+	}
+
+	if o.metered {
+		local.quota, local.metered = o.quota, true
+		remote.quota, remote.metered = o.quota, true
+	}
+
+	if !l.addConn() {
+		return nil, errTooManyConns
+	}
+
+	if l.sched != nil {
+		local.sched, local.id = l.sched, l.sched.register()
+		remote.sched, remote.id = l.sched, l.sched.register()
+
+		p1.sched, p1.wrid, p1.rdid = l.sched, local.id, remote.id
+		p2.sched, p2.wrid, p2.rdid = l.sched, remote.id, local.id
+	}
+
+	var herr error
+	if l.handshake != nil {
+		if herr = l.handshake(local, remote); herr != nil {
+			local.CloseWithError(herr)
+		}
+	}
+
+	connCh, _ := l.queue(o.label)
+
+	select {
+	case <-l.done:
+		local.Close()
+		remote.Close()
+		return nil, io.ErrClosedPipe
+	case <-ctx.Done():
+		local.Close()
+		remote.Close()
+		return nil, ctx.Err()
+	case connCh <- remote:
+		// The accepted end of a rejected pairing reads the error
+		if herr != nil {
+			return nil, herr
+		}
+
+		if d, ok := ctx.Deadline(); ok && o.ctxDeadline {
+			local.SetDeadline(d)
+		}
+		return local, nil
+	}
+}
+
+// NumConns returns the number of connections produced by the listener
+// which are still open. A connection stops counting once either of its
+// ends is closed.
+func (l *Listener) NumConns() int {
+	return int(atomic.LoadInt64(&l.nconns))
+}
+
+// DialTimeout is like Dial but gives up once d elapsed while waiting for
+// a free slot in the accept queue. A d <= 0 means no timeout.
+func (l *Listener) DialTimeout(d time.Duration, opts ...DialOption) (net.Conn, error) {
+	if d <= 0 {
+		return l.DialContext(context.Background(), opts...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return l.DialContext(ctx, opts...)
+}
+
+// Listen returns a *Listener which can queue connQSize number of
+// new connections till it blocks the call to Accept() and have
+//transport buffer size of transBuffSize, zero meaning unbuffered
+// conns like net.Pipe
+func Listen(connQSize, transBuffSize int, _addr string, opts ...Option) (*Listener, error) {
+	l := &Listener{
+		bsz:    transBuffSize,
+		connCh: make(chan net.Conn, connQSize),
+		done:   make(chan struct{}),
+		rewire: make(chan struct{}),
+		addr:   addr{_addr},
+		clock:  realClock{},
+	}
+
+	for _, opt := range opts {
+		opt(l)
 	}
 
 	return l, nil
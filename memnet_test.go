@@ -23,10 +23,20 @@
 package memnet
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
+	"os"
 	"reflect"
+	"runtime"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -49,6 +59,20 @@ const (
 	errMemListener    = "failed to start memlistener: %v"
 )
 
+var (
+	_ net.Conn        = (*conn)(nil)
+	_ io.ReaderFrom   = (*conn)(nil)
+	_ io.WriterTo     = (*conn)(nil)
+	_ io.StringWriter = (*conn)(nil)
+	_ net.Listener    = (*Listener)(nil)
+
+	// The half-close methods of *net.TCPConn
+	_ interface {
+		CloseRead() error
+		CloseWrite() error
+	} = (*conn)(nil)
+)
+
 type ioResult struct {
 	n   int
 	err error
@@ -303,3 +327,1262 @@ func TestLocalSetReadDeadline(t *testing.T) {
 		t.Fatalf("local.Read = _, %v, want %v", err, errTimeout)
 	}
 }
+
+func TestDialBufferSizeOverride(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	local, err := ln.DialContext(context.Background(),
+		WithReadBufferSize(64), WithWriteBufferSize(128))
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	if c := local.(*conn).Cap(); c != 64 {
+		t.Fatalf("local.Cap() = %d, want %d", c, 64)
+	}
+
+	if c := remote.(*conn).Cap(); c != 128 {
+		t.Fatalf("remote.Cap() = %d, want %d", c, 128)
+	}
+
+	other, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	if c := other.(*conn).Cap(); c != dLnOptn.t {
+		t.Fatalf("other.Cap() = %d, want %d", c, dLnOptn.t)
+	}
+}
+
+func TestCloseWithError(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	input := []byte("bye")
+	if _, err := local.Write(input); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	errPeer := fmt.Errorf("peer went away")
+	local.(*conn).CloseWithError(errPeer)
+
+	output := make([]byte, len(input))
+	if _, err := remote.Read(output); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	if !reflect.DeepEqual(input, output) {
+		t.Fatalf(errIOMismatched, input, output)
+	}
+
+	if _, err := remote.Read(output); err != errPeer {
+		t.Fatalf("remote.Read = _, %v, want %v", err, errPeer)
+	}
+}
+
+func TestListenerExpvar(t *testing.T) {
+	name := fmt.Sprintf("memnet_test_%d", time.Now().UnixNano())
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, WithExpvar(name))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	if _, err := local.Write([]byte("hello")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if _, err := remote.Write([]byte("hi")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if _, err := remote.(*conn).WritePriority([]byte("!"), true); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	var got map[string]int64
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &got); err != nil {
+		t.Fatalf("could not decode expvar: %v", err)
+	}
+
+	want := map[string]int64{"conns": 1, "bytes": 8, "max_occupancy": 5}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expvar = %v, want %v", got, want)
+	}
+
+	local.Close()
+	remote.Close()
+
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &got); err != nil {
+		t.Fatalf("could not decode expvar: %v", err)
+	}
+
+	if got["conns"] != 0 {
+		t.Fatalf("expvar conns = %d, want %d", got["conns"], 0)
+	}
+}
+
+func TestReadDeadlineBuffered(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	input := []byte("early")
+	if _, err := local.Write(input); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	remote.SetReadDeadline(time.Now().Add(-time.Second))
+	time.Sleep(10 * time.Millisecond)
+
+	output := make([]byte, 10)
+	n, err := remote.Read(output)
+	if n != len(input) || err != nil {
+		t.Fatalf("remote.Read = %d, %v, want %d, nil", n, err, len(input))
+	}
+
+	if !reflect.DeepEqual(input, output[:n]) {
+		t.Fatalf(errIOMismatched, input, output[:n])
+	}
+
+	if _, err := remote.Read(output); err != errTimeout {
+		t.Fatalf("remote.Read = _, %v, want %v", err, errTimeout)
+	}
+}
+
+func TestDialTimeout(t *testing.T) {
+	ln, err := Listen(1, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	// Fill up the backlog
+	if _, err := ln.DialTimeout(0); err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	_, err = ln.DialTimeout(50 * time.Millisecond)
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("ln.DialTimeout = _, %v, want timeout", err)
+	}
+
+	if _, err := ln.Accept(); err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	if _, err := ln.DialTimeout(50 * time.Millisecond); err != nil {
+		t.Fatalf("ln.DialTimeout = _, %v, want nil", err)
+	}
+}
+
+func TestListenerNumConns(t *testing.T) {
+	ln, err := Listen(5, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	var locals, remotes []net.Conn
+	for i := 0; i < 4; i++ {
+		local, err := ln.Dial()
+		if err != nil {
+			t.Fatalf(errMemServer, err.Error())
+		}
+		locals = append(locals, local)
+
+		remote, err := ln.Accept()
+		if err != nil {
+			t.Fatalf(errAcceptMemConn, err.Error())
+		}
+		remotes = append(remotes, remote)
+	}
+
+	if n := ln.NumConns(); n != 4 {
+		t.Fatalf("ln.NumConns() = %d, want %d", n, 4)
+	}
+
+	locals[0].Close()
+	remotes[1].Close()
+
+	// Closing the other end of an already closed conn doesn't count twice
+	remotes[0].Close()
+
+	if n := ln.NumConns(); n != 2 {
+		t.Fatalf("ln.NumConns() = %d, want %d", n, 2)
+	}
+}
+
+func TestConnSetNoDelay(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	elapsed := func() time.Duration {
+		readCh := doRead(remote, make([]byte, 1))
+		time.Sleep(10 * time.Millisecond)
+
+		start := time.Now()
+		if _, err := local.Write([]byte{1}); err != nil {
+			t.Fatalf(errWriteLocalConn, err.Error())
+		}
+
+		if result := <-readCh; result.err != nil {
+			t.Fatalf(errReadRemoteConn, result.err.Error())
+		}
+		return time.Since(start)
+	}
+
+	local.(*conn).SetNoDelay(false)
+	if d := elapsed(); d < coalesceDelay {
+		t.Fatalf("reader woke up after %v with NoDelay off, want >= %v", d, coalesceDelay)
+	}
+
+	local.(*conn).SetNoDelay(true)
+	if d := elapsed(); d >= coalesceDelay {
+		t.Fatalf("reader woke up after %v with NoDelay on, want < %v", d, coalesceDelay)
+	}
+}
+
+func TestClearReadDeadlineWhileBlocked(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	remote.SetReadDeadline(time.Now().Add(1 * time.Second))
+	output := make([]byte, 4)
+	readCh := doRead(remote, output)
+
+	time.Sleep(500 * time.Millisecond)
+	remote.SetReadDeadline(time.Time{})
+
+	time.Sleep(1500 * time.Millisecond)
+	input := []byte("late")
+	if _, err := local.Write(input); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	result := <-readCh
+	if result.n != len(input) || result.err != nil {
+		t.Fatalf("remote.Read = %d, %v, want %d, nil", result.n, result.err, len(input))
+	}
+
+	if !reflect.DeepEqual(input, output) {
+		t.Fatalf(errIOMismatched, input, output)
+	}
+}
+
+func TestSetWriteDeadline(t *testing.T) {
+	local, _, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	local.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+
+	_, err = local.Write(make([]byte, dLnOptn.t+1))
+	if err != errTimeout {
+		t.Fatalf("local.Write = _, %v, want %v", err, errTimeout)
+	}
+}
+
+func TestReadDeadlineExceeded(t *testing.T) {
+	local, _, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	local.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+
+	_, err = local.Read(make([]byte, 1))
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("local.Read = _, %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("local.Read = _, %v, want net.Error timeout", err)
+	}
+}
+
+func TestCloseWithStats(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := local.Write([]byte("leftover")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if _, err := remote.Read(make([]byte, 4)); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	unread, err := remote.(*conn).CloseWithStats()
+	if unread != 4 || err != nil {
+		t.Fatalf("remote.CloseWithStats() = %d, %v, want %d, nil", unread, err, 4)
+	}
+
+	unread, err = local.(*conn).CloseWithStats()
+	if unread != 0 || err != nil {
+		t.Fatalf("local.CloseWithStats() = %d, %v, want %d, nil", unread, err, 0)
+	}
+}
+
+func TestCloseDuringWrite(t *testing.T) {
+	const frame = 4
+
+	for i := 0; i < 50; i++ {
+		local, remote, err := memConnServe()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		readCh := make(chan []byte)
+		go func() {
+			b, _ := ioutil.ReadAll(remote)
+			readCh <- b
+		}()
+
+		var wg sync.WaitGroup
+		for w := 1; w <= 3; w++ {
+			wg.Add(1)
+			go func(w byte) {
+				defer wg.Done()
+				p := bytes.Repeat([]byte{w}, frame)
+				for {
+					n, err := local.Write(p)
+					if err != nil {
+						if err != io.ErrClosedPipe || n != 0 {
+							t.Errorf("local.Write = %d, %v, want 0, %v", n, err, io.ErrClosedPipe)
+						}
+						return
+					}
+				}
+			}(byte(w))
+		}
+
+		time.Sleep(time.Millisecond)
+		local.Close()
+		wg.Wait()
+
+		b := <-readCh
+		if len(b)%frame != 0 {
+			t.Fatalf("read %d bytes, want a multiple of %d", len(b), frame)
+		}
+
+		for j := 0; j < len(b); j += frame {
+			if !bytes.Equal(b[j:j+frame], bytes.Repeat(b[j:j+1], frame)) {
+				t.Fatalf("torn frame at %d: %v", j, b[j:j+frame])
+			}
+		}
+	}
+}
+
+func TestConnMaxLifetime(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	start := time.Now()
+	local.(*conn).SetMaxLifetime(50 * time.Millisecond)
+
+	if _, err := local.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("local.Read = _, %v, want %v", err, io.EOF)
+	}
+
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Fatalf("conn expired after %v, want >= %v", d, 50*time.Millisecond)
+	}
+
+	if _, err := local.Write([]byte{1}); err != io.ErrClosedPipe {
+		t.Fatalf("local.Write = _, %v, want %v", err, io.ErrClosedPipe)
+	}
+
+	if _, err := remote.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("remote.Read = _, %v, want %v", err, io.EOF)
+	}
+
+	if n := ln.NumConns(); n != 0 {
+		t.Fatalf("ln.NumConns() = %d, want %d", n, 0)
+	}
+
+	if err := local.Close(); err != nil {
+		t.Fatalf("local.Close() = %v, want nil", err)
+	}
+}
+
+func TestConnMaxLifetimeClosed(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	local.(*conn).SetMaxLifetime(20 * time.Millisecond)
+	local.Close()
+
+	// The lifetime must not fire on what is left of the conn
+	time.Sleep(40 * time.Millisecond)
+
+	if _, err := remote.Write([]byte{1}); err != io.ErrClosedPipe {
+		t.Fatalf("remote.Write = _, %v, want %v", err, io.ErrClosedPipe)
+	}
+
+	if err := remote.Close(); err != nil {
+		t.Fatalf("remote.Close() = %v, want nil", err)
+	}
+}
+
+func TestListenerPairsSynchronously(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	before := runtime.NumGoroutine()
+
+	// The dial is queued before the accept, neither hands off to a
+	// goroutine of its own
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	if n := runtime.NumGoroutine(); n != before {
+		t.Fatalf("runtime.NumGoroutine() = %d after pairing, want %d", n, before)
+	}
+
+	if !SamePeer(local, remote) {
+		t.Fatalf("Accept didn't return the peer of the dial")
+	}
+}
+
+func TestListenerHasPendingAccept(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	if ln.HasPendingAccept() {
+		t.Fatalf("ln.HasPendingAccept() = true before Accept")
+	}
+
+	acceptCh := make(chan error)
+	go func() {
+		_, err := ln.Accept()
+		acceptCh <- err
+	}()
+
+	for start := time.Now(); !ln.HasPendingAccept(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("ln.HasPendingAccept() never became true")
+		}
+	}
+
+	if _, err := ln.Dial(); err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	if err := <-acceptCh; err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	if ln.HasPendingAccept() {
+		t.Fatalf("ln.HasPendingAccept() = true after Accept returned")
+	}
+}
+
+func TestConnWritePriority(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	lc := local.(*conn)
+	writes := []struct {
+		p    string
+		high bool
+	}{
+		{"l1", false},
+		{"h1", true},
+		{"l2", false},
+		{"h2", true},
+	}
+
+	for _, w := range writes {
+		if _, err := lc.WritePriority([]byte(w.p), w.high); err != nil {
+			t.Fatalf(errWriteLocalConn, err.Error())
+		}
+	}
+	local.Close()
+
+	output, err := ioutil.ReadAll(remote)
+	if err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	if string(output) != "h1h2l1l2" {
+		t.Fatalf("remote read %q, want %q", output, "h1h2l1l2")
+	}
+}
+
+func TestConnInFlight(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	lc, rc := local.(*conn), remote.(*conn)
+	inFlight := func(want int) {
+		t.Helper()
+		if l, r := lc.InFlight(), rc.InFlight(); l != want || r != want {
+			t.Fatalf("InFlight() = %d, %d, want %d on both ends", l, r, want)
+		}
+	}
+
+	inFlight(0)
+
+	if _, err := local.Write([]byte("ping")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+	if _, err := remote.Write([]byte("pong!")); err != nil {
+		t.Fatalf("remote.Write = _, %v", err)
+	}
+	inFlight(9)
+
+	if _, err := remote.Read(make([]byte, 2)); err != nil {
+		t.Fatalf(errReadRemoteConn, err)
+	}
+	inFlight(7)
+}
+
+func TestConnResetStats(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	transfer := func(input string) {
+		t.Helper()
+		if _, err := local.Write([]byte(input)); err != nil {
+			t.Fatalf(errWriteLocalConn, err.Error())
+		}
+
+		if _, err := io.ReadFull(remote, make([]byte, len(input))); err != nil {
+			t.Fatalf(errReadRemoteConn, err.Error())
+		}
+	}
+
+	transfer("phase one")
+	local.(*conn).ResetStats()
+	remote.(*conn).ResetStats()
+
+	transfer("two")
+
+	if n := local.(*conn).Stats().BytesWritten; n != 3 {
+		t.Fatalf("local Stats().BytesWritten = %d, want %d", n, 3)
+	}
+
+	if n := remote.(*conn).Stats().BytesRead; n != 3 {
+		t.Fatalf("remote Stats().BytesRead = %d, want %d", n, 3)
+	}
+}
+
+func TestStatsConnectLatency(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	if d := local.(*conn).Stats().ConnectLatency; d != 0 {
+		t.Fatalf("ConnectLatency = %v before Accept, want 0", d)
+	}
+
+	delay := 50 * time.Millisecond
+	time.Sleep(delay)
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	for _, c := range []net.Conn{local, remote} {
+		if d := c.(*conn).Stats().ConnectLatency; d < delay {
+			t.Fatalf("ConnectLatency = %v, want >= %v", d, delay)
+		}
+	}
+}
+
+func TestDialContextDeadline(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	local, err := ln.DialContext(ctx, WithContextDeadline())
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	_, err = local.Read(make([]byte, 1))
+	if err != errTimeout {
+		t.Fatalf("local.Read = _, %v, want %v", err, errTimeout)
+	}
+
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Fatalf("local.Read timed out after %v, want >= %v", d, 50*time.Millisecond)
+	}
+
+	if _, err := ln.Accept(); err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	// Without the option the conn doesn't inherit the deadline
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	other, err := ln.DialContext(ctx)
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	time.AfterFunc(50*time.Millisecond, func() { remote.Write([]byte{1}) })
+	if _, err := other.Read(make([]byte, 1)); err != nil {
+		t.Fatalf("other.Read = _, %v, want nil", err)
+	}
+}
+
+func TestListenerRebind(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	old, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	oldRemote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	const rebound = "10.1.1.1:4434"
+	ln.Rebind(rebound)
+
+	if ln.Addr().String() != rebound {
+		t.Fatalf("ln.Addr() = %v, want %v", ln.Addr(), rebound)
+	}
+
+	cur, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	curRemote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	if old.RemoteAddr().String() != dLnOptn.a || oldRemote.LocalAddr().String() != dLnOptn.a {
+		t.Fatalf("old conn addrs = %v, %v, want %v", old.RemoteAddr(), oldRemote.LocalAddr(), dLnOptn.a)
+	}
+
+	if cur.RemoteAddr().String() != rebound || curRemote.LocalAddr().String() != rebound {
+		t.Fatalf("new conn addrs = %v, %v, want %v", cur.RemoteAddr(), curRemote.LocalAddr(), rebound)
+	}
+}
+
+func TestCloseAfterDrain(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	input := []byte("0123456789")
+	if _, err := local.Write(input); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- local.(*conn).CloseAfterDrain(context.Background())
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("CloseAfterDrain returned %v before the peer read", err)
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	output, err := ioutil.ReadAll(remote)
+	if err != nil {
+		t.Fatalf(errReadRemoteConn, err)
+	}
+
+	if !bytes.Equal(input, output) {
+		t.Fatalf(errIOMismatched, input, output)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("CloseAfterDrain = %v, want nil", err)
+	}
+
+	if err := local.Close(); err == nil {
+		t.Fatal("local.Close succeeded after CloseAfterDrain")
+	}
+}
+
+func TestCloseAfterDrainCtx(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := local.Write([]byte("abc")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := local.(*conn).CloseAfterDrain(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("CloseAfterDrain = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The bytes already sent are still readable after the close
+	output, err := ioutil.ReadAll(remote)
+	if err != nil || string(output) != "abc" {
+		t.Fatalf("ioutil.ReadAll = %q, %v, want %q, nil", output, err, "abc")
+	}
+}
+
+func TestConnReadFromWriteTo(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	input := bytes.Repeat([]byte("0123456789"), 100)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := local.(io.ReaderFrom).ReadFrom(bytes.NewReader(input))
+		if err == nil {
+			err = local.(*conn).CloseWrite()
+		}
+		done <- err
+	}()
+
+	var output bytes.Buffer
+	n, err := remote.(io.WriterTo).WriteTo(&output)
+	if err != nil || n != int64(len(input)) {
+		t.Fatalf("remote.WriteTo = %d, %v, want %d, nil", n, err, len(input))
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("local.ReadFrom = _, %v", err)
+	}
+
+	if !bytes.Equal(input, output.Bytes()) {
+		t.Fatalf(errIOMismatched, input, output.Bytes())
+	}
+
+	if _, err := remote.(io.StringWriter).WriteString("hi"); err != nil {
+		t.Fatalf("remote.WriteString = _, %v", err)
+	}
+
+	p := make([]byte, 2)
+	if _, err := io.ReadFull(local, p); err != nil || string(p) != "hi" {
+		t.Fatalf("io.ReadFull = %q, %v, want %q, nil", p, err, "hi")
+	}
+}
+
+func TestConnReadFromWriteToDeadline(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Nobody reads, so the copy stalls once the ring is full
+	local.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+
+	input := bytes.Repeat([]byte("0123456789"), 100)
+	n, err := io.Copy(local, bytes.NewReader(input))
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("io.Copy(local) = _, %v, want a timeout", err)
+	}
+
+	if c := int64(remote.(*conn).Cap()); n != c {
+		t.Fatalf("io.Copy(local) = %d, _, want %d", n, c)
+	}
+
+	// The peer never closes, so the copy stalls once the ring is drained
+	remote.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+
+	var output bytes.Buffer
+	n, err = io.Copy(&output, remote)
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Fatalf("io.Copy(remote) = _, %v, want a timeout", err)
+	}
+
+	if want := input[:n]; n != int64(remote.(*conn).Cap()) || !bytes.Equal(output.Bytes(), want) {
+		t.Fatalf("io.Copy(remote) = %d, %q, want %d, %q", n, output.Bytes(), len(want), want)
+	}
+}
+
+func TestConnCloseRead(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := remote.(*conn).CloseRead(); err != nil {
+		t.Fatalf("remote.CloseRead = %v", err)
+	}
+
+	if _, err := local.Write([]byte("a")); err != io.ErrClosedPipe {
+		t.Fatalf("local.Write = _, %v, want %v", err, io.ErrClosedPipe)
+	}
+
+	if _, err := remote.Read(make([]byte, 1)); err != io.ErrClosedPipe {
+		t.Fatalf("remote.Read = _, %v, want %v", err, io.ErrClosedPipe)
+	}
+
+	// The other direction still works
+	if _, err := remote.Write([]byte("b")); err != nil {
+		t.Fatalf("remote.Write = _, %v", err)
+	}
+
+	p := make([]byte, 1)
+	if _, err := local.Read(p); err != nil || string(p) != "b" {
+		t.Fatalf("local.Read = %q, %v, want %q, nil", p, err, "b")
+	}
+
+	if err := remote.Close(); err != nil {
+		t.Fatalf("remote.Close = %v, want nil", err)
+	}
+
+	if err := remote.Close(); err == nil {
+		t.Fatal("remote.Close succeeded twice")
+	}
+}
+
+func TestConnWaitReadable(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	rc := remote.(*conn)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- rc.WaitReadable(context.Background())
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("WaitReadable = %v before the peer wrote", err)
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	if _, err := local.Write([]byte("ab")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("WaitReadable = %v, want nil", err)
+	}
+
+	// Nothing was consumed
+	if err := rc.WaitReadable(context.Background()); err != nil {
+		t.Fatalf("WaitReadable = %v, want nil", err)
+	}
+
+	p := make([]byte, 2)
+	if _, err := io.ReadFull(remote, p); err != nil || string(p) != "ab" {
+		t.Fatalf("io.ReadFull = %q, %v, want %q, nil", p, err, "ab")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := rc.WaitReadable(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("WaitReadable = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	local.Close()
+	if err := rc.WaitReadable(context.Background()); err != io.EOF {
+		t.Fatalf("WaitReadable = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestConnSetWriteCoalesce(t *testing.T) {
+	clk := &fakeClock{}
+
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, withClock(clk))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	if _, err := ln.Accept(); err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	rb := local.(*conn).w.(*ringBuff)
+	readable := func() bool {
+		rb.mu.Lock()
+		defer rb.mu.Unlock()
+		return rb.readable()
+	}
+
+	const d = 10 * time.Millisecond
+	local.(*conn).SetWriteCoalesce(d)
+
+	for i := 0; i < 2; i++ {
+		if _, err := local.Write([]byte("a")); err != nil {
+			t.Fatalf(errWriteLocalConn, err.Error())
+		}
+
+		clk.Advance(d / 2)
+		if _, err := local.Write([]byte("b")); err != nil {
+			t.Fatalf(errWriteLocalConn, err.Error())
+		}
+
+		if readable() {
+			t.Fatalf("batch %d readable before the coalesce delay", i)
+		}
+
+		clk.Advance(d / 2)
+		if !readable() {
+			t.Fatalf("batch %d not readable after the coalesce delay", i)
+		}
+
+		// Drain the batch so the next one starts a new interval
+		if _, err := rb.Read(make([]byte, 2)); err != nil {
+			t.Fatalf(errReadRemoteConn, err)
+		}
+	}
+
+	// Filling up the buffer delivers right away
+	if _, err := local.Write(make([]byte, dLnOptn.t)); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if !readable() {
+		t.Fatal("full buffer not readable")
+	}
+}
+
+func TestConnSetWriteCoalesceStaleTimer(t *testing.T) {
+	clk := &fakeClock{}
+
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, withClock(clk))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	if _, err := ln.Accept(); err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	rb := local.(*conn).w.(*ringBuff)
+	readable := func() bool {
+		rb.mu.Lock()
+		defer rb.mu.Unlock()
+		return rb.readable()
+	}
+
+	const d = 10 * time.Millisecond
+	local.(*conn).SetWriteCoalesce(d)
+
+	if _, err := local.Write([]byte("a")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	// Right before the timer of the first batch fires, a full buffer
+	// flushes it and a second batch starts
+	clk.AfterFunc(d/2, func() {
+		if _, err := local.Write(make([]byte, dLnOptn.t-1)); err != nil {
+			t.Errorf(errWriteLocalConn, err.Error())
+		}
+
+		if _, err := io.ReadFull(rb, make([]byte, dLnOptn.t)); err != nil {
+			t.Errorf(errReadRemoteConn, err)
+		}
+
+		if _, err := local.Write([]byte("b")); err != nil {
+			t.Errorf(errWriteLocalConn, err.Error())
+		}
+	})
+
+	clk.Advance(d)
+	if readable() {
+		t.Fatal("second batch readable when the first batch's timer fired")
+	}
+
+	// The timers run once the clock is at d, so that's when the second
+	// batch started
+	clk.Advance(d)
+	if !readable() {
+		t.Fatal("second batch not readable after the coalesce delay")
+	}
+}
+
+func TestConnReadMulti(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Wrap the stream around the end of the ring
+	if _, err := local.Write([]byte("xxxx012345")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if _, err := io.ReadFull(remote, make([]byte, 4)); err != nil {
+		t.Fatalf(errReadRemoteConn, err)
+	}
+
+	if _, err := local.Write([]byte("6789")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	bufs := [][]byte{make([]byte, 3), make([]byte, 4), make([]byte, 5)}
+	n, err := remote.(*conn).ReadMulti(bufs)
+	if err != nil || n != 10 {
+		t.Fatalf("remote.ReadMulti = %d, %v, want %d, nil", n, err, 10)
+	}
+
+	got := string(bufs[0]) + string(bufs[1]) + string(bufs[2][:3])
+	if got != "0123456789" {
+		t.Fatalf(errIOMismatched, "0123456789", got)
+	}
+
+	local.Close()
+	if _, err := remote.(*conn).ReadMulti(bufs); err != io.EOF {
+		t.Fatalf("remote.ReadMulti = _, %v, want %v", err, io.EOF)
+	}
+}
+
+func TestConnAbort(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := local.Write([]byte("unread")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if err := local.(*conn).Abort(); err != nil {
+		t.Fatalf("local.Abort = %v", err)
+	}
+
+	// The buffered bytes are gone, the peer sees the reset right away
+	_, err = remote.Read(make([]byte, 8))
+	if err == io.EOF || !errors.Is(err, syscall.ECONNRESET) {
+		t.Fatalf("remote.Read = _, %v, want %v", err, errReset)
+	}
+
+	if nerr, ok := err.(net.Error); !ok || nerr.Timeout() {
+		t.Fatalf("remote.Read = _, %v, want a net.Error which is no timeout", err)
+	}
+
+	if _, err := remote.Write([]byte("a")); err != errReset {
+		t.Fatalf("remote.Write = _, %v, want %v", err, errReset)
+	}
+
+	if err := remote.Close(); err != nil {
+		t.Fatalf("remote.Close = %v, want nil", err)
+	}
+
+	if err := local.Close(); err == nil {
+		t.Fatal("local.Close succeeded after Abort")
+	}
+}
+
+func TestConnPeerClosedWrite(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	rc := remote.(*conn)
+	if rc.PeerClosedWrite() {
+		t.Fatal("PeerClosedWrite() = true before the peer closed")
+	}
+
+	if _, err := local.Write([]byte("ab")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if err := local.(*conn).CloseWrite(); err != nil {
+		t.Fatalf("local.CloseWrite = %v", err)
+	}
+
+	if !rc.PeerClosedWrite() {
+		t.Fatal("PeerClosedWrite() = false after the peer's CloseWrite")
+	}
+
+	// The buffered bytes come first, then the EOF the flag announced
+	output, err := ioutil.ReadAll(remote)
+	if err != nil || string(output) != "ab" {
+		t.Fatalf("ioutil.ReadAll = %q, %v, want %q, nil", output, err, "ab")
+	}
+
+	if local.(*conn).PeerClosedWrite() {
+		t.Fatal("PeerClosedWrite() = true on the end which closed")
+	}
+}
+
+func TestConcurrentReadDeadline(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				switch (i + j) % 3 {
+				case 0:
+					remote.SetReadDeadline(time.Time{})
+				case 1:
+					remote.SetReadDeadline(time.Now().Add(time.Millisecond))
+				case 2:
+					remote.SetReadDeadline(time.Now().Add(-time.Millisecond))
+				}
+			}
+		}(i)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			if _, err := local.Write([]byte("x")); err != nil {
+				return
+			}
+		}
+	}()
+
+	p := make([]byte, 4)
+	for start := time.Now(); time.Since(start) < 100*time.Millisecond; {
+		if _, err := remote.Read(p); err != nil && err != errTimeout {
+			t.Fatalf("remote.Read = _, %v", err)
+		}
+	}
+
+	close(stop)
+	local.(*conn).SetWriteDeadline(time.Now().Add(-time.Millisecond))
+	wg.Wait()
+
+	// Drain, then the last deadline set decides about the next read
+	remote.SetReadDeadline(time.Time{})
+	remote.SetReadDeadline(time.Now().Add(-time.Millisecond))
+	for {
+		if _, err := remote.Read(p); err != nil {
+			if err != errTimeout {
+				t.Fatalf("remote.Read = _, %v, want %v", err, errTimeout)
+			}
+			break
+		}
+	}
+
+	// A deadline set from elsewhere ends a blocked read
+	remote.SetReadDeadline(time.Time{})
+	readCh := doRead(remote, p)
+	time.Sleep(10 * time.Millisecond)
+	remote.SetReadDeadline(time.Now())
+
+	select {
+	case res := <-readCh:
+		if res.err != errTimeout {
+			t.Fatalf("remote.Read = _, %v, want %v", res.err, errTimeout)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked read ignored the deadline")
+	}
+}
@@ -0,0 +1,28 @@
+package memnet
+
+import "net"
+
+// Meta is the metadata a dialer hands to the server with WithMeta.
+type Meta map[string]string
+
+// WithMeta attaches a copy of m to the dialed conn, the server gets it
+// from AcceptMeta. It lets servers route on dial time information, like
+// the peer credentials of a unix socket, without a protocol exchange.
+func WithMeta(m Meta) DialOption {
+	return func(o *dialOptions) {
+		o.meta = make(Meta, len(m))
+		for k, v := range m {
+			o.meta[k] = v
+		}
+	}
+}
+
+// AcceptMeta is like Accept but also returns the metadata the conn was
+// dialed with, nil if there is none.
+func (l *Listener) AcceptMeta() (net.Conn, Meta, error) {
+	c, err := l.Accept()
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, c.(*conn).pair.meta, nil
+}
@@ -0,0 +1,39 @@
+package memnet
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAcceptMeta(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	meta := Meta{"tenant": "acme", "version": "2"}
+	if _, err := ln.DialContext(context.Background(), WithMeta(meta)); err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	// The dialer's map is copied
+	meta["tenant"] = "other"
+
+	_, got, err := ln.AcceptMeta()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	if len(got) != 2 || got["tenant"] != "acme" || got["version"] != "2" {
+		t.Fatalf("ln.AcceptMeta() = _, %v, want map[tenant:acme version:2]", got)
+	}
+
+	if _, err := ln.Dial(); err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	if _, got, err := ln.AcceptMeta(); err != nil || got != nil {
+		t.Fatalf("ln.AcceptMeta() = _, %v, %v, want nil, nil", got, err)
+	}
+}
@@ -0,0 +1,45 @@
+package memnet
+
+import (
+	"expvar"
+	"sync/atomic"
+)
+
+// metrics are the counters of a listener published through expvar.
+type metrics struct {
+	bytes     int64
+	occupancy int64
+}
+
+// wrote records n bytes written into a ring which now holds buffered
+// unread bytes.
+func (m *metrics) wrote(n, buffered int) {
+	atomic.AddInt64(&m.bytes, int64(n))
+
+	for {
+		max := atomic.LoadInt64(&m.occupancy)
+		if int64(buffered) <= max ||
+			atomic.CompareAndSwapInt64(&m.occupancy, max, int64(buffered)) {
+			return
+		}
+	}
+}
+
+// WithExpvar publishes the listener's live connection count, total bytes
+// written and the highest number of bytes buffered in a single direction
+// as the expvar name. Like expvar.Publish it panics if name is already
+// registered, so every listener needs its own name.
+func WithExpvar(name string) Option {
+	return func(l *Listener) {
+		m := &metrics{}
+		l.metrics = m
+
+		expvar.Publish(name, expvar.Func(func() interface{} {
+			return map[string]int64{
+				"conns":         int64(l.NumConns()),
+				"bytes":         atomic.LoadInt64(&m.bytes),
+				"max_occupancy": atomic.LoadInt64(&m.occupancy),
+			}
+		}))
+	}
+}
@@ -0,0 +1,19 @@
+package memnet
+
+// WithMTU makes the conns of the listener hand out at most n bytes per
+// Read, even if more is buffered, like a link which carries segments of
+// at most n bytes. Readers which assume a Read returns a whole message
+// fail against it as they would over a real network.
+func WithMTU(n int) Option {
+	return func(l *Listener) {
+		l.mtu = n
+	}
+}
+
+// segment cuts b down to the MTU of the conn.
+func (c *conn) segment(b []byte) []byte {
+	if c.mtu > 0 && len(b) > c.mtu {
+		return b[:c.mtu]
+	}
+	return b
+}
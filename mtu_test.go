@@ -0,0 +1,52 @@
+package memnet
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestWithMTU(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, 1024, dLnOptn.a, WithMTU(128))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	input := bytes.Repeat([]byte("0123456789"), 100)
+	if _, err := local.Write(input); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+	local.Close()
+
+	var output []byte
+	buf := make([]byte, len(input))
+	for {
+		n, err := remote.Read(buf)
+		if n > 128 {
+			t.Fatalf("remote.Read = %d, want at most %d", n, 128)
+		}
+		output = append(output, buf[:n]...)
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf(errReadRemoteConn, err.Error())
+		}
+	}
+
+	if !bytes.Equal(input, output) {
+		t.Fatalf(errIOMismatched, input, output)
+	}
+}
@@ -0,0 +1,17 @@
+package memnet
+
+import (
+	"fmt"
+	"net"
+)
+
+var errWouldBlock net.Error = netErrTemporary{fmt.Errorf("read would block")}
+
+// WithNonBlockingRead puts both ends of the dialed conn in non-blocking
+// mode, like O_NONBLOCK: a Read which would wait for the peer to send
+// fails right away with a temporary net.Error instead, for poll style
+// loops. Reads at the end of the stream still return io.EOF, and writes
+// block as usual.
+func WithNonBlockingRead() DialOption {
+	return func(o *dialOptions) { o.nonblock = true }
+}
@@ -0,0 +1,49 @@
+package memnet
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestNonBlockingRead(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	local, err := ln.DialContext(context.Background(), WithNonBlockingRead())
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	p := make([]byte, 4)
+	n, err := remote.Read(p)
+	if nerr, ok := err.(net.Error); n != 0 || !ok || !nerr.Temporary() || nerr.Timeout() {
+		t.Fatalf("remote.Read of an empty conn = %d, %v, want 0, a temporary error", n, err)
+	}
+
+	if _, err := local.Write([]byte("ping")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if n, err := remote.Read(p); n != 4 || err != nil {
+		t.Fatalf("remote.Read = %d, %v, want %d, nil", n, err, 4)
+	}
+
+	if _, err := remote.Read(p); err != errWouldBlock {
+		t.Fatalf("remote.Read of a drained conn = _, %v, want %v", err, errWouldBlock)
+	}
+
+	local.Close()
+	if _, err := remote.Read(p); err != io.EOF {
+		t.Fatalf("remote.Read of a closed conn = _, %v, want %v", err, io.EOF)
+	}
+}
@@ -0,0 +1,43 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package memnet
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// OSPipe returns the two ends of a unix socketpair. It is the fd backed
+// counterpart of a dialed and accepted memnet pair, for tests which need
+// a genuine file descriptor: both ends are net.Conns which implement
+// syscall.Conn.
+func OSPipe() (net.Conn, net.Conn, error) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		return nil, nil, os.NewSyscallError("socketpair", err)
+	}
+
+	c1, err := fileConn(fds[0], "memnet.ospipe.0")
+	if err != nil {
+		syscall.Close(fds[1])
+		return nil, nil, err
+	}
+
+	c2, err := fileConn(fds[1], "memnet.ospipe.1")
+	if err != nil {
+		c1.Close()
+		return nil, nil, err
+	}
+
+	return c1, c2, nil
+}
+
+// fileConn turns fd into a net.Conn, which holds a dup of it.
+func fileConn(fd int, name string) (net.Conn, error) {
+	f := os.NewFile(uintptr(fd), name)
+	defer f.Close()
+
+	return net.FileConn(f)
+}
@@ -0,0 +1,15 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package memnet
+
+import (
+	"fmt"
+	"net"
+)
+
+// OSPipe returns the two ends of a unix socketpair, which is not
+// available on this platform.
+func OSPipe() (net.Conn, net.Conn, error) {
+	return nil, nil, fmt.Errorf("socketpair not supported")
+}
@@ -0,0 +1,56 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package memnet
+
+import (
+	"io"
+	"syscall"
+	"testing"
+)
+
+func TestOSPipe(t *testing.T) {
+	c1, c2, err := OSPipe()
+	if err != nil {
+		t.Fatalf("OSPipe = _, _, %v", err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	input := []byte("over a real fd")
+	writeCh := doWrite(c1, input)
+
+	output := make([]byte, len(input))
+	if _, err := io.ReadFull(c2, output); err != nil {
+		t.Fatalf(errReadRemoteConn, err)
+	}
+
+	if res := <-writeCh; res.err != nil {
+		t.Fatalf(errWriteLocalConn, res.err)
+	}
+
+	if string(input) != string(output) {
+		t.Fatalf(errIOMismatched, input, output)
+	}
+
+	for _, c := range []interface{}{c1, c2} {
+		sc, ok := c.(syscall.Conn)
+		if !ok {
+			t.Fatalf("%T does not implement syscall.Conn", c)
+		}
+
+		rc, err := sc.SyscallConn()
+		if err != nil {
+			t.Fatalf("SyscallConn = _, %v", err)
+		}
+
+		fd := -1
+		if err := rc.Control(func(f uintptr) { fd = int(f) }); err != nil {
+			t.Fatalf("Control = %v", err)
+		}
+
+		if fd < 0 {
+			t.Fatalf("fd = %d, want a valid descriptor", fd)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package memnet
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	errAddrInUse = fmt.Errorf("address already in use")
+	errNoRoute   = fmt.Errorf("no route to host")
+	errMsgSize   = fmt.Errorf("message too long")
+)
+
+// defaultMaxMessageSize is the largest UDP payload over IPv4.
+const defaultMaxMessageSize = 65507
+
+// PacketNet is an in-memory fabric which routes datagrams between the
+// PacketConns bound on it by their address.
+type PacketNet struct {
+	// MaxMessageSize is the largest datagram WriteTo accepts, it
+	// defaults to the UDP limit when zero. Set it before use.
+	MaxMessageSize int
+
+	mu    sync.Mutex
+	conns map[string]*PacketConn
+}
+
+// NewPacketNet returns an empty fabric.
+func NewPacketNet() *PacketNet {
+	return &PacketNet{conns: make(map[string]*PacketConn)}
+}
+
+// ListenPacket returns a *PacketConn bound to _addr on the fabric which
+// can queue queueSize number of datagrams till it blocks the senders.
+func (pn *PacketNet) ListenPacket(queueSize int, _addr string) (*PacketConn, error) {
+	pn.mu.Lock()
+	defer pn.mu.Unlock()
+
+	if _, ok := pn.conns[_addr]; ok {
+		return nil, errAddrInUse
+	}
+
+	pc := &PacketConn{
+		pn:    pn,
+		addr:  addr{_addr},
+		qsize: queueSize,
+	}
+	pc.rdwait.L = &pc.mu
+	pc.wrwait.L = &pc.mu
+
+	pn.conns[_addr] = pc
+	return pc, nil
+}
+
+func (pn *PacketNet) lookup(a net.Addr) *PacketConn {
+	pn.mu.Lock()
+	defer pn.mu.Unlock()
+
+	return pn.conns[a.String()]
+}
+
+// wakeSenders wakes the WriteTo calls blocked on full queues, so that
+// they notice a write deadline which passed.
+func (pn *PacketNet) wakeSenders() {
+	pn.mu.Lock()
+	conns := make([]*PacketConn, 0, len(pn.conns))
+	for _, pc := range pn.conns {
+		conns = append(conns, pc)
+	}
+	pn.mu.Unlock()
+
+	for _, pc := range conns {
+		pc.mu.Lock()
+		pc.wrwait.Broadcast()
+		pc.mu.Unlock()
+	}
+}
+
+type packet struct {
+	b    []byte
+	from net.Addr
+}
+
+// PacketConn satisfies net.PacketConn
+type PacketConn struct {
+	// wrtimeout is accessed atomically as it is checked by WriteTo
+	// while holding the lock of the destination
+	wrtimeout int32
+
+	pn   *PacketNet
+	addr net.Addr
+
+	mu     sync.Mutex
+	rdwait sync.Cond
+	wrwait sync.Cond
+	queue  []packet
+	qsize  int
+	closed bool
+
+	rdtimer   *time.Timer
+	wrtimer   *time.Timer
+	rdtimeout bool
+}
+
+// ReadFrom reads the next queued datagram into p and reports the address
+// it was sent from. Like UDP, bytes which don't fit in p are discarded.
+func (pc *PacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	for {
+		if pc.closed {
+			return 0, nil, io.ErrClosedPipe
+		}
+
+		if len(pc.queue) > 0 {
+			break
+		}
+
+		if pc.rdtimeout {
+			return 0, nil, errTimeout
+		}
+
+		pc.rdwait.Wait()
+	}
+
+	pkt := pc.queue[0]
+	pc.queue[0] = packet{}
+	pc.queue = pc.queue[1:]
+
+	// Wake the writers waiting on a full queue. All of them, as the one
+	// a Signal picks may be leaving on a deadline instead of taking the
+	// free slot.
+	pc.wrwait.Broadcast()
+
+	return copy(p, pkt.b), pkt.from, nil
+}
+
+// WriteTo sends p as a single datagram to the PacketConn bound to a on
+// the same fabric, blocking while its queue is full. The write deadline
+// only fails a WriteTo which has to wait for room in that queue.
+func (pc *PacketConn) WriteTo(p []byte, a net.Addr) (int, error) {
+	pc.mu.Lock()
+	closed := pc.closed
+	pc.mu.Unlock()
+
+	if closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	max := pc.pn.MaxMessageSize
+	if max == 0 {
+		max = defaultMaxMessageSize
+	}
+
+	if len(p) > max {
+		return 0, errMsgSize
+	}
+
+	dst := pc.pn.lookup(a)
+	if dst == nil {
+		return 0, errNoRoute
+	}
+
+	b := make([]byte, len(p))
+	copy(b, p)
+
+	if err := dst.enqueue(packet{b, pc.addr}, pc); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// enqueue waits for room in the queue of pc, or until the write deadline
+// of the sender from passed.
+func (pc *PacketConn) enqueue(pkt packet, from *PacketConn) error {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	for {
+		if pc.closed {
+			return errNoRoute
+		}
+
+		if len(pc.queue) < pc.qsize {
+			break
+		}
+
+		if atomic.LoadInt32(&from.wrtimeout) == 1 {
+			return errTimeout
+		}
+
+		pc.wrwait.Wait()
+	}
+
+	pc.queue = append(pc.queue, pkt)
+
+	// Wake the readers waiting on an empty queue, all of them for the
+	// same reason as in ReadFrom
+	pc.rdwait.Broadcast()
+	return nil
+}
+
+// Close unbinds the PacketConn from the fabric and unblocks pending
+// reads and writes.
+func (pc *PacketConn) Close() error {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if pc.closed {
+		return io.ErrClosedPipe
+	}
+	pc.closed = true
+
+	pc.pn.mu.Lock()
+	delete(pc.pn.conns, pc.addr.String())
+	pc.pn.mu.Unlock()
+
+	pc.rdwait.Broadcast()
+	pc.wrwait.Broadcast()
+	return nil
+}
+
+// FramesBuffered returns the sizes of the datagrams queued for ReadFrom,
+// oldest first, so tests can assert on how the writes were framed.
+func (pc *PacketConn) FramesBuffered() []int {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	sizes := make([]int, len(pc.queue))
+	for i, pkt := range pc.queue {
+		sizes[i] = len(pkt.b)
+	}
+	return sizes
+}
+
+func (pc *PacketConn) LocalAddr() net.Addr { return pc.addr }
+
+func (pc *PacketConn) SetDeadline(t time.Time) error {
+	pc.SetReadDeadline(t)
+	pc.SetWriteDeadline(t)
+	return nil
+}
+
+// SetReadDeadline makes a ReadFrom which waits for a datagram fail with
+// a timeout once t passed. A zero t clears the deadline.
+func (pc *PacketConn) SetReadDeadline(t time.Time) error {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if pc.rdtimer != nil {
+		pc.rdtimer.Stop()
+		pc.rdtimer = nil
+	}
+	pc.rdtimeout = false
+
+	if t.IsZero() {
+		return nil
+	}
+
+	d := time.Until(t)
+	if d <= 0 {
+		pc.rdtimeout = true
+		pc.rdwait.Broadcast()
+		return nil
+	}
+
+	var tm *time.Timer
+	tm = time.AfterFunc(d, func() {
+		pc.mu.Lock()
+		defer pc.mu.Unlock()
+
+		// The deadline was changed while the timer fired
+		if pc.rdtimer != tm {
+			return
+		}
+
+		pc.rdtimeout = true
+		pc.rdwait.Broadcast()
+	})
+	pc.rdtimer = tm
+	return nil
+}
+
+// SetWriteDeadline makes a WriteTo which waits for room in the
+// destination's queue fail with a timeout once t passed. A zero t clears
+// the deadline.
+func (pc *PacketConn) SetWriteDeadline(t time.Time) error {
+	pc.mu.Lock()
+
+	if pc.wrtimer != nil {
+		pc.wrtimer.Stop()
+		pc.wrtimer = nil
+	}
+	atomic.StoreInt32(&pc.wrtimeout, 0)
+
+	if t.IsZero() {
+		pc.mu.Unlock()
+		return nil
+	}
+
+	d := time.Until(t)
+	if d <= 0 {
+		atomic.StoreInt32(&pc.wrtimeout, 1)
+		pc.mu.Unlock()
+		pc.pn.wakeSenders()
+		return nil
+	}
+
+	var tm *time.Timer
+	tm = time.AfterFunc(d, func() {
+		pc.mu.Lock()
+
+		// The deadline was changed while the timer fired
+		if pc.wrtimer != tm {
+			pc.mu.Unlock()
+			return
+		}
+
+		atomic.StoreInt32(&pc.wrtimeout, 1)
+		pc.mu.Unlock()
+
+		// The senders wait on the queue of their destination
+		pc.pn.wakeSenders()
+	})
+	pc.wrtimer = tm
+
+	pc.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,192 @@
+package memnet
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var _ net.PacketConn = (*PacketConn)(nil)
+
+func TestPacketRouting(t *testing.T) {
+	pn := NewPacketNet()
+
+	names := []string{"10.0.0.1:53", "10.0.0.2:53", "10.0.0.3:53"}
+	pcs := make([]*PacketConn, len(names))
+	for i, name := range names {
+		pc, err := pn.ListenPacket(4, name)
+		if err != nil {
+			t.Fatalf("pn.ListenPacket(%q) = _, %v", name, err)
+		}
+		defer pc.Close()
+		pcs[i] = pc
+	}
+
+	if _, err := pn.ListenPacket(4, names[0]); err != errAddrInUse {
+		t.Fatalf("pn.ListenPacket = _, %v, want %v", err, errAddrInUse)
+	}
+
+	// Every endpoint sends its own name to every other endpoint
+	for i, src := range pcs {
+		for j, dst := range pcs {
+			if i == j {
+				continue
+			}
+			if _, err := src.WriteTo([]byte(names[i]), dst.LocalAddr()); err != nil {
+				t.Fatalf("%s.WriteTo(%s) = _, %v", names[i], names[j], err)
+			}
+		}
+	}
+
+	buf := make([]byte, 64)
+	for j, dst := range pcs {
+		seen := make(map[string]bool)
+		for k := 0; k < len(pcs)-1; k++ {
+			n, from, err := dst.ReadFrom(buf)
+			if err != nil {
+				t.Fatalf("%s.ReadFrom = _, _, %v", names[j], err)
+			}
+
+			if string(buf[:n]) != from.String() {
+				t.Fatalf("%s.ReadFrom = %q from %v", names[j], buf[:n], from)
+			}
+
+			if from.String() == names[j] || seen[from.String()] {
+				t.Fatalf("%s.ReadFrom got unexpected datagram from %v", names[j], from)
+			}
+			seen[from.String()] = true
+		}
+	}
+
+	if _, err := pcs[0].WriteTo(nil, addr{"10.0.0.9:53"}); err != errNoRoute {
+		t.Fatalf("WriteTo(unbound) = _, %v, want %v", err, errNoRoute)
+	}
+}
+
+func TestPacketMaxMessageSize(t *testing.T) {
+	pn := NewPacketNet()
+	pn.MaxMessageSize = 8
+
+	a, err := pn.ListenPacket(1, "a")
+	if err != nil {
+		t.Fatalf("pn.ListenPacket = _, %v", err)
+	}
+
+	b, err := pn.ListenPacket(1, "b")
+	if err != nil {
+		t.Fatalf("pn.ListenPacket = _, %v", err)
+	}
+
+	if _, err := a.WriteTo(make([]byte, 9), b.LocalAddr()); err != errMsgSize {
+		t.Fatalf("a.WriteTo(9 bytes) = _, %v, want %v", err, errMsgSize)
+	}
+
+	if n, err := a.WriteTo(make([]byte, 8), b.LocalAddr()); n != 8 || err != nil {
+		t.Fatalf("a.WriteTo(8 bytes) = %d, %v, want %d, nil", n, err, 8)
+	}
+
+	// The oversized datagram never got queued
+	n, _, err := b.ReadFrom(make([]byte, 16))
+	if n != 8 || err != nil {
+		t.Fatalf("b.ReadFrom = %d, _, %v, want %d, nil", n, err, 8)
+	}
+}
+
+func TestPacketReadDeadline(t *testing.T) {
+	pn := NewPacketNet()
+
+	pc, err := pn.ListenPacket(1, "10.0.0.1:53")
+	if err != nil {
+		t.Fatalf("pn.ListenPacket = _, %v", err)
+	}
+	defer pc.Close()
+
+	pc.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+
+	buf := make([]byte, 8)
+	if _, _, err := pc.ReadFrom(buf); err != errTimeout {
+		t.Fatalf("pc.ReadFrom = _, _, %v, want %v", err, errTimeout)
+	}
+
+	pc.SetReadDeadline(time.Time{})
+
+	if _, err := pc.WriteTo([]byte("ping"), pc.LocalAddr()); err != nil {
+		t.Fatalf("pc.WriteTo = _, %v", err)
+	}
+
+	if n, _, err := pc.ReadFrom(buf); err != nil || string(buf[:n]) != "ping" {
+		t.Fatalf("pc.ReadFrom = %q, _, %v, want %q, _, nil", buf[:n], err, "ping")
+	}
+}
+
+func TestPacketWriteDeadline(t *testing.T) {
+	pn := NewPacketNet()
+
+	src, err := pn.ListenPacket(1, "10.0.0.1:53")
+	if err != nil {
+		t.Fatalf("pn.ListenPacket = _, %v", err)
+	}
+	defer src.Close()
+
+	dst, err := pn.ListenPacket(1, "10.0.0.2:53")
+	if err != nil {
+		t.Fatalf("pn.ListenPacket = _, %v", err)
+	}
+	defer dst.Close()
+
+	// Fill up the queue of dst
+	if _, err := src.WriteTo([]byte("a"), dst.LocalAddr()); err != nil {
+		t.Fatalf("src.WriteTo = _, %v", err)
+	}
+
+	src.SetWriteDeadline(time.Now().Add(20 * time.Millisecond))
+
+	if _, err := src.WriteTo([]byte("b"), dst.LocalAddr()); err != errTimeout {
+		t.Fatalf("src.WriteTo = _, %v, want %v", err, errTimeout)
+	}
+
+	src.SetWriteDeadline(time.Time{})
+
+	buf := make([]byte, 8)
+	if _, _, err := dst.ReadFrom(buf); err != nil {
+		t.Fatalf("dst.ReadFrom = _, _, %v", err)
+	}
+
+	if _, err := src.WriteTo([]byte("c"), dst.LocalAddr()); err != nil {
+		t.Fatalf("src.WriteTo = _, %v", err)
+	}
+}
+
+func TestPacketFramesBuffered(t *testing.T) {
+	pn := NewPacketNet()
+
+	pc, err := pn.ListenPacket(4, "10.0.0.1:53")
+	if err != nil {
+		t.Fatalf("pn.ListenPacket = _, %v", err)
+	}
+	defer pc.Close()
+
+	if sizes := pc.FramesBuffered(); len(sizes) != 0 {
+		t.Fatalf("pc.FramesBuffered() = %v, want none", sizes)
+	}
+
+	want := []int{3, 0, 512}
+	for _, n := range want {
+		if _, err := pc.WriteTo(make([]byte, n), pc.LocalAddr()); err != nil {
+			t.Fatalf("pc.WriteTo = _, %v", err)
+		}
+	}
+
+	if sizes := pc.FramesBuffered(); !reflect.DeepEqual(sizes, want) {
+		t.Fatalf("pc.FramesBuffered() = %v, want %v", sizes, want)
+	}
+
+	if _, _, err := pc.ReadFrom(make([]byte, 1)); err != nil {
+		t.Fatalf("pc.ReadFrom = _, _, %v", err)
+	}
+
+	if sizes := pc.FramesBuffered(); !reflect.DeepEqual(sizes, want[1:]) {
+		t.Fatalf("pc.FramesBuffered() = %v, want %v", sizes, want[1:])
+	}
+}
@@ -0,0 +1,67 @@
+package memnet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+)
+
+var errPaused net.Error = netErrTemporary{fmt.Errorf("listener is not accepting")}
+
+// WithRejectWhilePaused makes dials fail with a temporary net.Error while
+// the listener is paused, instead of blocking until it resumes.
+func WithRejectWhilePaused() Option {
+	return func(l *Listener) {
+		l.rejectPaused = true
+	}
+}
+
+// PauseAccept makes the listener stop taking new connections without
+// closing it, like an overloaded server. Conns already queued can still
+// be accepted. Dials block until ResumeAccept, or fail right away with
+// WithRejectWhilePaused.
+func (l *Listener) PauseAccept() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.resume == nil {
+		l.resume = make(chan struct{})
+	}
+}
+
+// ResumeAccept undoes PauseAccept and releases the blocked dials.
+func (l *Listener) ResumeAccept() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.resume != nil {
+		close(l.resume)
+		l.resume = nil
+	}
+}
+
+// waitResumed returns once the listener isn't paused.
+func (l *Listener) waitResumed(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		resume := l.resume
+		l.mu.Unlock()
+
+		if resume == nil {
+			return nil
+		}
+
+		if l.rejectPaused {
+			return errPaused
+		}
+
+		select {
+		case <-resume:
+		case <-l.done:
+			return io.ErrClosedPipe
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package memnet
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPauseAcceptBlocks(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	ln.PauseAccept()
+
+	dialCh := make(chan error)
+	go func() {
+		_, err := ln.Dial()
+		dialCh <- err
+	}()
+
+	select {
+	case err := <-dialCh:
+		t.Fatalf("ln.Dial = _, %v while paused, want it to block", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ln.ResumeAccept()
+
+	if err := <-dialCh; err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	if _, err := ln.Accept(); err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+}
+
+func TestPauseAcceptReject(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, WithRejectWhilePaused())
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	ln.PauseAccept()
+
+	_, err = ln.Dial()
+	if nerr, ok := err.(net.Error); !ok || !nerr.Temporary() {
+		t.Fatalf("ln.Dial = _, %v while paused, want temporary error", err)
+	}
+
+	ln.ResumeAccept()
+
+	if _, err := ln.Dial(); err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+}
@@ -0,0 +1,39 @@
+package memnet
+
+import "net"
+
+// SamePeer reports whether a and b are the two ends of the same memnet
+// connection, which makes it easy to assert on how a fabric routed its
+// dials. Conns wrapped by NewBufferedConn, WithTimeout and
+// NewContextConn are looked through.
+func SamePeer(a, b net.Conn) bool {
+	ca, ok := unwrapConn(a)
+	if !ok {
+		return false
+	}
+
+	cb, ok := unwrapConn(b)
+	if !ok {
+		return false
+	}
+
+	return ca != cb && ca.pair.id == cb.pair.id
+}
+
+// unwrapConn returns the memnet conn behind c.
+func unwrapConn(c net.Conn) (*conn, bool) {
+	for {
+		switch v := c.(type) {
+		case *conn:
+			return v, true
+		case *BufferedConn:
+			c = v.Conn
+		case *timeoutConn:
+			c = v.Conn
+		case *ctxConn:
+			c = v.Conn
+		default:
+			return nil, false
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package memnet
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSamePeer(t *testing.T) {
+	f := NewFabric(2, dLnOptn.t)
+
+	lns := make(map[string]*Listener)
+	for _, name := range []string{"a", "b"} {
+		ln, err := f.Listen(name)
+		if err != nil {
+			t.Fatalf(errMemListener, err.Error())
+		}
+		lns[name] = ln
+	}
+
+	ab, err := f.Dial("a", "b")
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	ba, err := f.Dial("b", "a")
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remoteAB, err := lns["b"].Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	remoteBA, err := lns["a"].Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	c1, c2 := net.Pipe()
+
+	for _, tc := range []struct {
+		a, b net.Conn
+		want bool
+	}{
+		{ab, remoteAB, true},
+		{remoteBA, ba, true},
+		{NewBufferedConn(ab, 16), WithTimeout(remoteAB, time.Second), true},
+		{ab, ab, false},
+		{ab, remoteBA, false},
+		{ba, remoteAB, false},
+		{c1, c2, false},
+		{ab, c2, false},
+	} {
+		if got := SamePeer(tc.a, tc.b); got != tc.want {
+			t.Fatalf("SamePeer(%v->%v, %v->%v) = %v, want %v",
+				tc.a.LocalAddr(), tc.a.RemoteAddr(), tc.b.LocalAddr(), tc.b.RemoteAddr(), got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package memnet
+
+import "context"
+
+// writeSync hands data to the readers of a ring of zero capacity and
+// waits until they consumed all of it. Concurrent writes are served one
+// after another. rb.mu must be held.
+func (rb *ringBuff) writeSync(ctx context.Context, data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	if err := rb.pause(ctx, rb.transitDelay(len(data))); err != nil {
+		return 0, err
+	}
+
+	ticket, err := rb.joinWriters(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if ticket != 0 {
+		defer func() {
+			rb.wrline.leave(ticket)
+			rb.wrwait.Broadcast()
+		}()
+	}
+
+	// Wait for the write in progress to be consumed, then for it to be
+	// our turn in the writer queue and with the half-duplex token
+	for {
+		if rb.closed || rb.writeClosed {
+			return 0, rb.errClosedPipe()
+		}
+
+		if rb.pending == nil && rb.writerTurn(ticket) && rb.duplex.mayWrite(rb) {
+			break
+		}
+
+		if rb.wrtimeout {
+			return 0, errTimeout
+		}
+
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		rb.wait(&rb.wrwait, rb.wrid)
+	}
+
+	rb.duplex.take(rb)
+	rb.pending = data
+	rb.watchdogWrote()
+	rb.rdwait.Broadcast()
+
+	// The next writer may go once pending is nil again
+	defer func() {
+		rb.pending, rb.dropped = nil, 0
+		rb.wrwait.Broadcast()
+	}()
+
+	for {
+		n := len(data) - len(rb.pending) - rb.dropped
+
+		// A reset throws away what wasn't read yet
+		if rb.rsterr != nil {
+			return n, rb.rsterr
+		}
+
+		if len(rb.pending) == 0 {
+			if rb.metrics != nil {
+				rb.metrics.wrote(n, 0)
+			}
+			return n, nil
+		}
+
+		if rb.closed || rb.writeClosed {
+			return n, rb.errClosedPipe()
+		}
+
+		if rb.wrtimeout {
+			return n, errTimeout
+		}
+
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+
+		rb.wait(&rb.wrwait, rb.wrid)
+	}
+}
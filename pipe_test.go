@@ -0,0 +1,115 @@
+package memnet
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func pipeServe(t *testing.T) (*conn, *conn) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	local, err := ln.DialContext(context.Background(), WithBufferSize(0))
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	return local.(*conn), remote.(*conn)
+}
+
+func TestUnbufferedWriteBlocks(t *testing.T) {
+	local, remote := pipeServe(t)
+
+	if local.Cap() != 0 || remote.Cap() != 0 {
+		t.Fatalf("Cap() = %d, %d, want 0, 0", local.Cap(), remote.Cap())
+	}
+
+	writeCh := doWrite(local, []byte("abc"))
+
+	blocked := func() {
+		t.Helper()
+		select {
+		case result := <-writeCh:
+			t.Fatalf("local.Write = %d, %v before it was read", result.n, result.err)
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	blocked()
+
+	output := make([]byte, 2)
+	if n, err := remote.Read(output); n != 2 || err != nil {
+		t.Fatalf("remote.Read = %d, %v, want %d, nil", n, err, 2)
+	}
+
+	blocked()
+
+	if n, err := remote.Read(output); n != 1 || err != nil || output[0] != 'c' {
+		t.Fatalf("remote.Read = %d, %v, want %d, nil", n, err, 1)
+	}
+
+	if result := <-writeCh; result.n != 3 || result.err != nil {
+		t.Fatalf("local.Write = %d, %v, want %d, nil", result.n, result.err, 3)
+	}
+}
+
+func TestUnbufferedDeadlineAndClose(t *testing.T) {
+	local, remote := pipeServe(t)
+
+	local.SetWriteDeadline(time.Now().Add(20 * time.Millisecond))
+	if n, err := local.Write([]byte("abc")); n != 0 || err != errTimeout {
+		t.Fatalf("local.Write = %d, %v, want 0, %v", n, err, errTimeout)
+	}
+
+	remote.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	if _, err := remote.Read(make([]byte, 1)); err != errTimeout {
+		t.Fatalf("remote.Read = _, %v, want %v", err, errTimeout)
+	}
+
+	local.SetWriteDeadline(time.Time{})
+	writeCh := doWrite(local, []byte("abc"))
+
+	remote.SetReadDeadline(time.Time{})
+	if n, err := remote.Read(make([]byte, 1)); n != 1 || err != nil {
+		t.Fatalf("remote.Read = %d, %v, want %d, nil", n, err, 1)
+	}
+
+	remote.Close()
+	if result := <-writeCh; result.n != 1 || result.err != io.ErrClosedPipe {
+		t.Fatalf("local.Write = %d, %v, want %d, %v", result.n, result.err, 1, io.ErrClosedPipe)
+	}
+
+	if _, err := local.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("local.Read = _, %v, want %v", err, io.EOF)
+	}
+}
+
+func TestUnbufferedWriteAbort(t *testing.T) {
+	local, remote := pipeServe(t)
+
+	writeCh := doWrite(remote, []byte("hello"))
+	waitWaiters(t, remote.w.(*ringBuff), 0, 1)
+
+	// Part of the write is read before the reset drops the rest
+	output := make([]byte, 2)
+	if n, err := local.Read(output); n != 2 || err != nil {
+		t.Fatalf("local.Read = %d, %v, want 2, nil", n, err)
+	}
+
+	if err := local.Abort(); err != nil {
+		t.Fatalf("local.Abort = %v", err)
+	}
+
+	if result := <-writeCh; result.n != 2 || result.err != errReset {
+		t.Fatalf("remote.Write = %d, %v, want 2, %v", result.n, result.err, errReset)
+	}
+}
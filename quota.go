@@ -0,0 +1,48 @@
+package memnet
+
+import "fmt"
+
+var errQuotaExceeded = fmt.Errorf("write quota exceeded")
+
+// WithWriteQuota caps the total number of bytes each end of the dialed
+// conn can write at n, like a metered link. The Write which crosses the
+// cap writes what is left of the quota and fails with the partial count,
+// the Writes after it fail right away.
+func WithWriteQuota(n int64) DialOption {
+	return func(o *dialOptions) { o.quota, o.metered = n, true }
+}
+
+// reserve takes the bytes of b off the write quota and returns the part
+// of b which fits into the quota, with errQuotaExceeded if that is not
+// all of it.
+func (c *conn) reserve(b []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.metered {
+		return b, nil
+	}
+
+	if int64(len(b)) <= c.quota {
+		c.quota -= int64(len(b))
+		return b, nil
+	}
+
+	b = b[:c.quota]
+	c.quota = 0
+	return b, errQuotaExceeded
+}
+
+// refund gives back n reserved bytes which didn't get written.
+func (c *conn) refund(n int) {
+	if n <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.metered {
+		c.quota += int64(n)
+	}
+}
@@ -0,0 +1,50 @@
+package memnet
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+func TestWithWriteQuota(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	local, err := ln.DialContext(context.Background(), WithWriteQuota(6))
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	if n, err := local.Write([]byte("abcd")); n != 4 || err != nil {
+		t.Fatalf("local.Write = %d, %v, want %d, nil", n, err, 4)
+	}
+
+	if n, err := local.Write([]byte("efgh")); n != 2 || err != errQuotaExceeded {
+		t.Fatalf("local.Write = %d, %v, want %d, %v", n, err, 2, errQuotaExceeded)
+	}
+
+	if n, err := local.Write([]byte("i")); n != 0 || err != errQuotaExceeded {
+		t.Fatalf("local.Write = %d, %v, want %d, %v", n, err, 0, errQuotaExceeded)
+	}
+
+	output := make([]byte, 6)
+	if _, err := io.ReadFull(remote, output); err != nil {
+		t.Fatalf(errReadRemoteConn, err)
+	}
+
+	if string(output) != "abcdef" {
+		t.Fatalf(errIOMismatched, "abcdef", output)
+	}
+
+	// The quota is per end
+	if _, err := remote.Write([]byte("abcdef")); err != nil {
+		t.Fatalf("remote.Write = _, %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package memnet
+
+import (
+	"fmt"
+	"io"
+	"net"
+)
+
+var (
+	errTooLarge = fmt.Errorf("read limit exceeded")
+	errBadLimit = fmt.Errorf("invalid read limit")
+)
+
+// ReadAllMax reads from c till io.EOF like ioutil.ReadAll, but gives up
+// with errTooLarge once the peer sent more than max bytes, returning the
+// first max of them. Deadlines set on c apply to each of the reads. A
+// negative max fails without reading anything.
+func ReadAllMax(c net.Conn, max int) ([]byte, error) {
+	if max < 0 {
+		return nil, errBadLimit
+	}
+
+	buf := make([]byte, 0, 512)
+	for {
+		if len(buf) == cap(buf) {
+			buf = append(buf, 0)[:len(buf)]
+		}
+
+		// One byte more than max tells an exact fit from an overflow
+		limit := cap(buf)
+		if limit > max+1 {
+			limit = max + 1
+		}
+
+		n, err := c.Read(buf[len(buf):limit])
+		buf = buf[:len(buf)+n]
+
+		if len(buf) > max {
+			return buf[:max], errTooLarge
+		}
+
+		if err == io.EOF {
+			return buf, nil
+		}
+		if err != nil {
+			return buf, err
+		}
+	}
+}
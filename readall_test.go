@@ -0,0 +1,89 @@
+package memnet
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestReadAllMax(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	input := []byte("0123456789")
+	if _, err := local.Write(input); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+	local.Close()
+
+	output, err := ReadAllMax(remote, len(input))
+	if err != nil {
+		t.Fatalf("ReadAllMax = _, %v, want nil", err)
+	}
+
+	if !bytes.Equal(input, output) {
+		t.Fatalf(errIOMismatched, input, output)
+	}
+}
+
+func TestReadAllMaxTooLarge(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	input := []byte("0123456789")
+	if _, err := local.Write(input); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	// The peer never closes, the limit has to end the read
+	output, err := ReadAllMax(remote, 4)
+	if err != errTooLarge {
+		t.Fatalf("ReadAllMax = _, %v, want %v", err, errTooLarge)
+	}
+
+	if !bytes.Equal(input[:4], output) {
+		t.Fatalf(errIOMismatched, input[:4], output)
+	}
+}
+
+func TestReadAllMaxNegative(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := local.Write([]byte("abc")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if output, err := ReadAllMax(remote, -1); output != nil || err != errBadLimit {
+		t.Fatalf("ReadAllMax(-1) = %q, %v, want nil, %v", output, err, errBadLimit)
+	}
+
+	// Nothing was read
+	if n := remote.(*conn).Buffered(); n != 3 {
+		t.Fatalf("Buffered() = %d, want 3", n)
+	}
+}
+
+func TestReadAllMaxDeadline(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := local.Write([]byte("abc")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	remote.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+
+	output, err := ReadAllMax(remote, 64)
+	if err != errTimeout || string(output) != "abc" {
+		t.Fatalf("ReadAllMax = %q, %v, want %q, %v", output, err, "abc", errTimeout)
+	}
+}
@@ -0,0 +1,97 @@
+package memnet
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+)
+
+// readUntil reads till and including the first delim, by scanning the
+// readable bytes in place. On failure it returns what it consumed so far.
+func (rb *ringBuff) readUntil(ctx context.Context, delim byte) ([]byte, error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	defer rb.watch(ctx, &rb.rdwait)()
+
+	var line []byte
+	for {
+		if err := rb.awaitReadable(ctx); err != nil {
+			return line, err
+		}
+
+		chunk := rb.chunk()
+
+		n := bytes.IndexByte(chunk, delim) + 1
+		if n == 0 {
+			n = len(chunk)
+		}
+
+		line = append(line, make([]byte, n)...)
+		rb.take(line[len(line)-n:])
+
+		if line[len(line)-1] == delim {
+			return line, nil
+		}
+	}
+}
+
+// ReadUntil reads till and including the first delim, like
+// bufio.Reader.ReadBytes, without consuming anything past it. It blocks
+// till delim arrives and fails like Read on EOF, deadlines and close,
+// returning the bytes read before the failure.
+func (c *conn) ReadUntil(delim byte) ([]byte, error) {
+	if c.gz != nil {
+		return c.readUntilCopy(delim)
+	}
+
+	if c.sched != nil {
+		c.sched.acquire(c.id)
+		defer c.sched.release(c.id)
+	}
+
+	line, err := c.r.(*ringBuff).readUntil(context.Background(), delim)
+	atomic.AddInt64(&c.nread, int64(len(line)))
+	c.pair.transcript.record(c.dialer, false, line)
+
+	// A timeout doesn't end the stream for the tees
+	terr := err
+	if terr == errTimeout || terr == errWouldBlock {
+		terr = nil
+	}
+
+	if len(line) > 0 || terr != nil {
+		c.tee(line, terr)
+	}
+	return line, err
+}
+
+// readUntilCopy is ReadUntil for the conns which have to decode what they
+// read, it reads a byte at a time.
+func (c *conn) readUntilCopy(delim byte) ([]byte, error) {
+	var line []byte
+	b := make([]byte, 1)
+
+	for {
+		n, err := c.Read(b)
+		line = append(line, b[:n]...)
+
+		if err != nil {
+			return line, err
+		}
+
+		if n > 0 && b[0] == delim {
+			return line, nil
+		}
+	}
+}
+
+// WriteLine writes s followed by '\n', in a single Write so that the
+// line is framed for ReadUntil('\n') on the peer. Like ReadUntil, it
+// only runs into a deadline which passed once it has to wait, and
+// returns what it wrote before a failure, the '\n' included.
+func (c *conn) WriteLine(s string) (int, error) {
+	b := make([]byte, len(s)+1)
+	copy(b, s)
+	b[len(s)] = '\n'
+	return c.Write(b)
+}
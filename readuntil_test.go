@@ -0,0 +1,130 @@
+package memnet
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestConnReadUntil(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []DialOption
+	}{
+		{"plain", nil},
+		{"compressed", []DialOption{WithTransparentCompression()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+			if err != nil {
+				t.Fatalf(errMemListener, err.Error())
+			}
+
+			local, err := ln.DialContext(context.Background(), tc.opts...)
+			if err != nil {
+				t.Fatalf(errMemServer, err.Error())
+			}
+
+			remote, err := ln.Accept()
+			if err != nil {
+				t.Fatalf(errAcceptMemConn, err.Error())
+			}
+
+			// The records wrap around the ring and span several writes
+			go func() {
+				for _, s := range []string{"one\ntw", "o\n", "three\nla", "st"} {
+					if _, err := local.Write([]byte(s)); err != nil {
+						return
+					}
+				}
+				local.Close()
+			}()
+
+			rc := remote.(*conn)
+			for _, want := range []string{"one\n", "two\n", "three\n"} {
+				line, err := rc.ReadUntil('\n')
+				if err != nil || string(line) != want {
+					t.Fatalf("ReadUntil = %q, %v, want %q, nil", line, err, want)
+				}
+			}
+
+			line, err := rc.ReadUntil('\n')
+			if err != io.EOF || string(line) != "last" {
+				t.Fatalf("ReadUntil = %q, %v, want %q, %v", line, err, "last", io.EOF)
+			}
+		})
+	}
+}
+
+func TestConnReadUntilDeadline(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := local.Write([]byte("partial")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	remote.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+
+	line, err := remote.(*conn).ReadUntil('\n')
+	if err != errTimeout || string(line) != "partial" {
+		t.Fatalf("ReadUntil = %q, %v, want %q, %v", line, err, "partial", errTimeout)
+	}
+}
+
+func TestConnWriteLine(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	lc, rc := local.(*conn), remote.(*conn)
+	lines := []string{"HELO memnet", "", "MAIL FROM:<a@b>", "QUIT"}
+
+	// The lines outgrow the ring, so they're written aside
+	done := make(chan error, 1)
+	go func() {
+		for _, s := range lines {
+			if _, err := lc.WriteLine(s); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	for _, s := range lines {
+		line, err := rc.ReadUntil('\n')
+		if err != nil || string(line) != s+"\n" {
+			t.Fatalf("ReadUntil = %q, %v, want %q, nil", line, err, s+"\n")
+		}
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("WriteLine = _, %v", err)
+	}
+
+	// Past their deadlines both sides still move whole lines while they
+	// don't have to wait, and give up with a timeout once they would
+	lc.SetWriteDeadline(time.Now().Add(-time.Second))
+	rc.SetReadDeadline(time.Now().Add(-time.Second))
+
+	if n, err := lc.WriteLine("123456789"); n != dLnOptn.t || err != nil {
+		t.Fatalf("WriteLine = %d, %v, want %d, nil", n, err, dLnOptn.t)
+	}
+
+	if n, err := lc.WriteLine("late"); n != 0 || err != errTimeout {
+		t.Fatalf("WriteLine to a full ring = %d, %v, want 0, %v", n, err, errTimeout)
+	}
+
+	if line, err := rc.ReadUntil('\n'); err != nil || string(line) != "123456789\n" {
+		t.Fatalf("ReadUntil = %q, %v, want %q, nil", line, err, "123456789\n")
+	}
+
+	if line, err := rc.ReadUntil('\n'); len(line) != 0 || err != errTimeout {
+		t.Fatalf("ReadUntil from an empty ring = %q, %v, want \"\", %v", line, err, errTimeout)
+	}
+}
@@ -0,0 +1,27 @@
+package memnet
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// errRefused is what a refused dial fails with by default, the error of
+// a TCP dial to a port nobody listens on.
+var errRefused net.Error = netErrPermanent{os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+
+// WithRefuseDials makes every dial to the listener fail with err, as if
+// the connection was refused, to exercise the retry logic of clients. A
+// nil err refuses with an error matching syscall.ECONNREFUSED, and an
+// err which isn't a net.Error gets wrapped into one.
+func WithRefuseDials(err error) Option {
+	if err == nil {
+		err = errRefused
+	} else if _, ok := err.(net.Error); !ok {
+		err = netErrPermanent{err}
+	}
+
+	return func(l *Listener) {
+		l.refuse = err
+	}
+}
@@ -0,0 +1,45 @@
+package memnet
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestWithRefuseDials(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, WithRefuseDials(nil))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	_, err = ln.Dial()
+	if nerr, ok := err.(net.Error); !ok || nerr.Timeout() || nerr.Temporary() {
+		t.Fatalf("ln.Dial = _, %v, want a permanent net.Error", err)
+	}
+
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("ln.Dial = _, %v, want %v", err, syscall.ECONNREFUSED)
+	}
+
+	if ln.NumConns() != 0 {
+		t.Fatalf("ln.NumConns() = %d, want 0", ln.NumConns())
+	}
+}
+
+func TestWithRefuseDialsCustom(t *testing.T) {
+	errGone := errors.New("service gone")
+
+	f := NewFabric(dLnOptn.c, dLnOptn.t)
+	if _, err := f.Listen("client"); err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	if _, err := f.Listen("svc", WithRefuseDials(errGone)); err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+
+	_, err := f.Dial("client", "svc")
+	if _, ok := err.(net.Error); !ok || !errors.Is(err, errGone) {
+		t.Fatalf("f.Dial = _, %v, want a net.Error wrapping %v", err, errGone)
+	}
+}
@@ -0,0 +1,73 @@
+package memnet
+
+import (
+	"net"
+	"sync"
+	"testing"
+)
+
+// rigBufSize is the buffer size of the conns of a test rig.
+const rigBufSize = 4 << 10
+
+// NewTestRig stands up a listener for a protocol test which serves every
+// conn it accepts by calling handler in its own goroutine, and returns
+// the func which dials it. When the test is done the listener and all
+// the conns of both ends are closed, and the handlers waited for, so a
+// handler must return once its conn fails.
+func NewTestRig(t testing.TB, handler func(net.Conn)) func() (net.Conn, error) {
+	t.Helper()
+
+	ln, err := Listen(1, rigBufSize, "memnet.rig")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var conns []net.Conn
+	track := func(c net.Conn) {
+		mu.Lock()
+		conns = append(conns, c)
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			track(c)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				handler(c)
+			}()
+		}
+	}()
+
+	t.Cleanup(func() {
+		ln.Close()
+
+		mu.Lock()
+		for _, c := range conns {
+			c.Close()
+		}
+		mu.Unlock()
+
+		wg.Wait()
+	})
+
+	return func() (net.Conn, error) {
+		c, err := ln.Dial()
+		if err != nil {
+			return nil, err
+		}
+		track(c)
+		return c, nil
+	}
+}
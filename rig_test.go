@@ -0,0 +1,29 @@
+package memnet
+
+import (
+	"io"
+	"net"
+	"testing"
+)
+
+func TestTestRigEcho(t *testing.T) {
+	dial := NewTestRig(t, func(c net.Conn) {
+		io.Copy(c, c)
+	})
+
+	for _, input := range []string{"hello", "memnet", "rig"} {
+		c, err := dial()
+		if err != nil {
+			t.Fatalf(errMemServer, err.Error())
+		}
+
+		if _, err := c.Write([]byte(input)); err != nil {
+			t.Fatalf("c.Write(%q) = %v", input, err)
+		}
+
+		output := make([]byte, len(input))
+		if _, err := io.ReadFull(c, output); err != nil || string(output) != input {
+			t.Fatalf("io.ReadFull = %q, %v, want %q, nil", output, err, input)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package memnet
+
+import (
+	"io"
+	"net"
+	"time"
+)
+
+// RoundTrip writes req to c and reads exactly respLen bytes back, the
+// lockstep exchange of most protocol tests. Both have to be done within
+// d, otherwise RoundTrip fails with the timeout error and the part of
+// the response read so far. A d <= 0 means no time limit. The deadline
+// is cleared again before RoundTrip returns. A negative respLen fails
+// without writing req.
+func RoundTrip(c net.Conn, req []byte, respLen int, d time.Duration) ([]byte, error) {
+	if respLen < 0 {
+		return nil, errBadLimit
+	}
+
+	if d > 0 {
+		if err := c.SetDeadline(time.Now().Add(d)); err != nil {
+			return nil, err
+		}
+		defer c.SetDeadline(time.Time{})
+	}
+
+	if _, err := c.Write(req); err != nil {
+		return nil, err
+	}
+
+	resp := make([]byte, respLen)
+	n, err := io.ReadFull(c, resp)
+	return resp[:n], err
+}
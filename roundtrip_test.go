@@ -0,0 +1,59 @@
+package memnet
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Echo the first 4 bytes only
+	go io.CopyN(remote, remote, 4)
+
+	resp, err := RoundTrip(local, []byte("ping"), 4, time.Second)
+	if err != nil || string(resp) != "ping" {
+		t.Fatalf("RoundTrip = %q, %v, want %q, nil", resp, err, "ping")
+	}
+
+	resp, err = RoundTrip(local, []byte("ping"), 4, 50*time.Millisecond)
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() || len(resp) != 0 {
+		t.Fatalf("RoundTrip without an echo = %q, %v, want a timeout", resp, err)
+	}
+
+	// The deadline doesn't outlive the call
+	done := make(chan error, 1)
+	go func() {
+		_, err := local.Read(make([]byte, 1))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("local.Read after RoundTrip = _, %v, want it to block", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	remote.Write([]byte("!"))
+	<-done
+}
+
+func TestRoundTripNegativeLength(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if resp, err := RoundTrip(local, []byte("ping"), -1, time.Second); resp != nil || err != errBadLimit {
+		t.Fatalf("RoundTrip(-1) = %q, %v, want nil, %v", resp, err, errBadLimit)
+	}
+
+	// The request wasn't sent
+	if n := remote.(*conn).Buffered(); n != 0 {
+		t.Fatalf("remote.Buffered() = %d, want 0", n)
+	}
+}
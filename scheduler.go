@@ -0,0 +1,123 @@
+package memnet
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// WithScheduler makes every Read and Write on the listener's conns take
+// a single turn token, handed out in an order drawn from seed. Running
+// the same scenario with the same seed replays the same interleaving.
+//
+// This is a debugging aid for reproducing races and it serializes all
+// I/O. The turn is only handed out once every open conn is waiting for
+// it, so each conn must be driven by exactly one goroutine which keeps
+// issuing operations until it closes the conn. Deadlines fire from
+// timers and make the order nondeterministic again.
+func WithScheduler(seed int64) Option {
+	return func(l *Listener) {
+		l.sched = newScheduler(seed)
+	}
+}
+
+type scheduler struct {
+	mu   sync.Mutex
+	cond sync.Cond
+	rnd  *rand.Rand
+
+	nextID  int
+	live    map[int]bool
+	waiting map[int]bool
+
+	// turn is the id of the conn which holds the token, -1 if none.
+	turn int
+}
+
+func newScheduler(seed int64) *scheduler {
+	s := &scheduler{
+		rnd:     rand.New(rand.NewSource(seed)),
+		live:    make(map[int]bool),
+		waiting: make(map[int]bool),
+		turn:    -1,
+	}
+	s.cond.L = &s.mu
+	return s
+}
+
+func (s *scheduler) register() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	s.live[id] = true
+	return id
+}
+
+func (s *scheduler) unregister(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.live, id)
+	delete(s.waiting, id)
+	if s.turn == id {
+		s.turn = -1
+	}
+
+	s.dispatch()
+	s.cond.Broadcast()
+}
+
+// acquire blocks until id holds the turn. Unregistered ids pass through.
+func (s *scheduler) acquire(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.live[id] {
+		return
+	}
+
+	s.waiting[id] = true
+	s.dispatch()
+
+	for s.live[id] && s.turn != id {
+		s.cond.Wait()
+	}
+}
+
+func (s *scheduler) release(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.turn != id {
+		return
+	}
+
+	s.turn = -1
+	s.dispatch()
+}
+
+// yield gives up the turn and waits to be picked again.
+func (s *scheduler) yield(id int) {
+	s.release(id)
+	s.acquire(id)
+}
+
+// dispatch hands the turn to one of the waiting conns once all of the
+// live conns are waiting. s.mu must be held.
+func (s *scheduler) dispatch() {
+	if s.turn != -1 || len(s.waiting) == 0 || len(s.waiting) < len(s.live) {
+		return
+	}
+
+	ids := make([]int, 0, len(s.waiting))
+	for id := range s.waiting {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	s.turn = ids[s.rnd.Intn(len(ids))]
+	delete(s.waiting, s.turn)
+	s.cond.Broadcast()
+}
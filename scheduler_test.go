@@ -0,0 +1,83 @@
+package memnet
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func schedulerRun(seed int64) ([]string, error) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, WithScheduler(seed))
+	if err != nil {
+		return nil, fmt.Errorf(errMemListener, err.Error())
+	}
+
+	local, err := ln.Dial()
+	if err != nil {
+		return nil, fmt.Errorf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		return nil, fmt.Errorf(errAcceptMemConn, err.Error())
+	}
+
+	var (
+		mu  sync.Mutex
+		log []string
+		wg  sync.WaitGroup
+	)
+
+	drive := func(name string, c net.Conn) {
+		defer wg.Done()
+		defer c.Close()
+
+		p := make([]byte, 1)
+		for i := 0; i < 4; i++ {
+			if _, err := c.Write([]byte{byte(i)}); err != nil {
+				return
+			}
+			mu.Lock()
+			log = append(log, fmt.Sprintf("%s write %d", name, i))
+			mu.Unlock()
+
+			if _, err := c.Read(p); err != nil {
+				return
+			}
+			mu.Lock()
+			log = append(log, fmt.Sprintf("%s read %d", name, p[0]))
+			mu.Unlock()
+		}
+	}
+
+	wg.Add(2)
+	go drive("local", local)
+	go drive("remote", remote)
+	wg.Wait()
+
+	return log, nil
+}
+
+func TestSchedulerReplay(t *testing.T) {
+	first, err := schedulerRun(42)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(first) != 16 {
+		t.Fatalf("len(log) = %d, want %d: %v", len(first), 16, first)
+	}
+
+	for i := 0; i < 5; i++ {
+		again, err := schedulerRun(42)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if !reflect.DeepEqual(first, again) {
+			t.Fatalf("replay diverged:\n%v\n%v", first, again)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package memnet
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// WithSequenceCheck is a debug mode which verifies that the bytes of the
+// dialed conn come out in the order they went in. Every chunk written
+// into the buffers is stamped with a sequence number and kept aside, and
+// every read is compared against the chunks due next. A mismatch panics
+// naming the write, which makes bugs in the simulation itself, like a
+// misbehaving fault injector, show up right where they happen.
+//
+// The mode keeps a copy of all the unread bytes. The writes of
+// unbuffered conns are handed to the readers as they are and not
+// checked.
+func WithSequenceCheck() DialOption {
+	return func(o *dialOptions) { o.seqCheck = true }
+}
+
+// Lanes of a seqCheck
+const (
+	seqMain = iota
+	seqPrio
+)
+
+// seqCheck is the record of the unread chunks written into a ring,
+// guarded by the ring's mutex. A nil *seqCheck checks nothing.
+type seqCheck struct {
+	next  uint64
+	lanes [2][]seqWrite
+}
+
+type seqWrite struct {
+	seq uint64
+	b   []byte
+}
+
+// wrote stamps b, which got written into lane.
+func (sc *seqCheck) wrote(lane int, b []byte) {
+	if sc == nil || len(b) == 0 {
+		return
+	}
+
+	sc.next++
+	sc.lanes[lane] = append(sc.lanes[lane], seqWrite{sc.next, append([]byte(nil), b...)})
+}
+
+// read checks b, which got read from lane, against the chunks due next.
+func (sc *seqCheck) read(lane int, b []byte) {
+	if sc == nil {
+		return
+	}
+
+	q := sc.lanes[lane]
+	for len(b) > 0 {
+		if len(q) == 0 {
+			panic("memnet: sequence check: read bytes which were never written")
+		}
+
+		w := &q[0]
+		n := len(b)
+		if n > len(w.b) {
+			n = len(w.b)
+		}
+
+		if !bytes.Equal(b[:n], w.b[:n]) {
+			panic(fmt.Sprintf("memnet: sequence check: bytes of write #%d arrived out of order", w.seq))
+		}
+
+		w.b, b = w.b[n:], b[n:]
+		if len(w.b) == 0 {
+			q = q[1:]
+		}
+	}
+	sc.lanes[lane] = q
+}
+
+// reset forgets the chunks which got discarded unread.
+func (sc *seqCheck) reset() {
+	if sc != nil {
+		sc.lanes = [2][]seqWrite{}
+	}
+}
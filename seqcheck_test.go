@@ -0,0 +1,68 @@
+package memnet
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSequenceCheck(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	local, err := ln.DialContext(context.Background(), WithSequenceCheck())
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	// A clean conn reads back its writes in order, across the wrap of
+	// the ring and through the high priority lane
+	for _, input := range []string{"0123456", "789ab", "cdefgh"} {
+		if _, err := local.Write([]byte(input)); err != nil {
+			t.Fatalf(errWriteLocalConn, err.Error())
+		}
+
+		if _, err := local.(*conn).WritePriority([]byte("!"), true); err != nil {
+			t.Fatalf(errWriteLocalConn, err.Error())
+		}
+
+		output := make([]byte, len(input)+1)
+		if _, err := io.ReadFull(remote, output); err != nil {
+			t.Fatalf(errReadRemoteConn, err.Error())
+		}
+
+		if want := "!" + input; string(output) != want {
+			t.Fatalf(errIOMismatched, want, output)
+		}
+	}
+
+	if _, err := local.Write([]byte("ijkl")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	// Reorder the buffered bytes behind the conn's back
+	rb := remote.(*conn).r.(*ringBuff)
+	rb.mu.Lock()
+	chunk := rb.chunk()
+	chunk[0], chunk[1] = chunk[1], chunk[0]
+	rb.mu.Unlock()
+
+	defer func() {
+		msg, _ := recover().(string)
+		if !strings.Contains(msg, "arrived out of order") {
+			t.Fatalf("recover() = %q, want the reordering detected", msg)
+		}
+	}()
+
+	remote.Read(make([]byte, 4))
+	t.Fatalf("Read of reordered bytes didn't panic")
+}
@@ -0,0 +1,31 @@
+package memnet
+
+import (
+	"io"
+	"math"
+	"net"
+)
+
+// Splice moves the bytes buffered in src, and all of those which follow,
+// to dst till src reaches EOF, which makes an in-memory proxy a couple of
+// lines. It returns the number of bytes moved, and a nil error on EOF
+// like io.Copy. A src from this package is written to dst a chunk of its
+// buffer at a time, like CopyN, so a dst which blocks doesn't keep src
+// from being closed. Other conns go through the WriteTo and ReadFrom
+// fast paths of io.Copy.
+func Splice(dst, src net.Conn) (int64, error) {
+	sc, ok := src.(*conn)
+	if !ok {
+		return io.Copy(dst, src)
+	}
+
+	n, ok, err := sc.copyDirect(dst, math.MaxInt64)
+	if !ok {
+		return io.Copy(dst, src)
+	}
+
+	if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
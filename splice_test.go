@@ -0,0 +1,126 @@
+package memnet
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSpliceChain(t *testing.T) {
+	// Three conns, the accepted end of each is spliced into the next
+	var locals, remotes []net.Conn
+	for i := 0; i < 3; i++ {
+		local, remote, err := memConnServe()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		locals, remotes = append(locals, local), append(remotes, remote)
+	}
+
+	spliced := make(chan int64, 2)
+	for i := 0; i < 2; i++ {
+		go func(dst, src net.Conn) {
+			n, err := Splice(dst, src)
+			if err != nil {
+				t.Errorf("Splice = %d, %v", n, err)
+			}
+			dst.(*conn).CloseWrite()
+			spliced <- n
+		}(locals[i+1], remotes[i])
+	}
+
+	// The payload outgrows the small buffers of the conns many times
+	input := bytes.Repeat([]byte("0123456789abcdef"), 64)
+
+	go func() {
+		locals[0].Write(input)
+		locals[0].(*conn).CloseWrite()
+	}()
+
+	output, err := ioutil.ReadAll(remotes[2])
+	if err != nil {
+		t.Fatalf(errReadRemoteConn, err)
+	}
+
+	if !bytes.Equal(output, input) {
+		t.Fatalf(errIOMismatched, input, output)
+	}
+
+	for i := 0; i < 2; i++ {
+		if n := <-spliced; n != int64(len(input)) {
+			t.Fatalf("Splice = %d, want %d", n, len(input))
+		}
+	}
+}
+
+func TestSpliceForeignConn(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	pr, pw := net.Pipe()
+	go func() {
+		io.WriteString(pw, "through a net.Pipe")
+		pw.Close()
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := Splice(local, pr)
+		local.Close()
+		done <- err
+	}()
+
+	output, err := ioutil.ReadAll(remote)
+	if err != nil || string(output) != "through a net.Pipe" {
+		t.Fatalf("ReadAll = %q, %v", output, err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("Splice = _, %v", err)
+	}
+}
+
+func TestSpliceCloseSrcWhileDstFull(t *testing.T) {
+	srcPeer, src, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	dst, dstPeer, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Nobody reads dstPeer, so Splice ends up blocked on dst
+	go srcPeer.Write(bytes.Repeat([]byte("x"), 4*dLnOptn.t))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := Splice(dst, src)
+		done <- err
+	}()
+
+	waitWaiters(t, dst.(*conn).w.(*ringBuff), 0, 1)
+
+	closed := make(chan struct{})
+	go func() {
+		src.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("src.Close blocked on the full dst")
+	}
+
+	dstPeer.Close()
+	if err := <-done; err != io.ErrClosedPipe {
+		t.Fatalf("Splice = _, %v, want %v", err, io.ErrClosedPipe)
+	}
+}
@@ -0,0 +1,105 @@
+package memnet
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestStressWakeups has many readers and writers move tiny chunks
+// through a tiny ring, with writes three times the size of the reads. Every
+// reader takes a fixed share, so a reader which misses its wakeup while
+// bytes are buffered hangs the test.
+func TestStressWakeups(t *testing.T) {
+	const goroutines, share = 16, 300
+
+	ln, err := Listen(dLnOptn.c, 3, dLnOptn.a)
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < share; j += 3 {
+				if _, err := local.Write([]byte{byte(j), byte(j + 1), byte(j + 2)}); err != nil {
+					t.Errorf(errWriteLocalConn, err.Error())
+					return
+				}
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			p := make([]byte, 1)
+			for j := 0; j < share; j++ {
+				if _, err := remote.Read(p); err != nil {
+					t.Errorf(errReadRemoteConn, err.Error())
+					return
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		rb := remote.(*conn).r.(*ringBuff)
+		rb.mu.Lock()
+		buffered := rb.buffered()
+		rb.mu.Unlock()
+		t.Fatalf("stalled with %d bytes buffered, a wakeup got lost", buffered)
+	}
+}
+
+func TestWriteWakesAllReaders(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	readCh := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := remote.Read(make([]byte, 1))
+			readCh <- err
+		}()
+	}
+
+	waitWaiters(t, remote.(*conn).r.(*ringBuff), 2, 0)
+
+	// One write is enough for both readers
+	if _, err := local.Write([]byte("ab")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-readCh:
+			if err != nil {
+				t.Fatalf(errReadRemoteConn, err.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("reader %d wasn't woken by the write", i)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package memnet
+
+import "io"
+
+// Tee returns a reader which receives a copy of every byte read from the
+// conn from now on. Reads on the conn block while the tee's buffer is
+// full, so the tee has to be read along with the conn or closed. The tee
+// reads io.EOF once the conn is closed or has read to the end.
+func (c *conn) Tee() io.ReadCloser {
+	size := c.Cap()
+	if size == 0 {
+		size = 1
+	}
+
+	rb := newRingBuff(size)
+
+	c.mu.Lock()
+	c.tees = append(c.tees, rb)
+	c.mu.Unlock()
+
+	return &Buffer{rb}
+}
+
+// tee copies p into every tee, and ends them if err terminates the
+// stream. Tees which were closed by their reader are dropped.
+func (c *conn) tee(p []byte, err error) {
+	c.mu.Lock()
+	tees := c.tees
+	c.mu.Unlock()
+
+	if len(tees) == 0 {
+		return
+	}
+
+	end := err != nil
+	if err == io.EOF {
+		err = nil
+	}
+
+	var dead []*ringBuff
+	for _, rb := range tees {
+		if len(p) > 0 {
+			if _, werr := rb.Write(p); werr != nil {
+				dead = append(dead, rb)
+				continue
+			}
+		}
+
+		if end {
+			rb.closeWrite(err)
+			dead = append(dead, rb)
+		}
+	}
+
+	if len(dead) == 0 {
+		return
+	}
+
+	// The tees may have changed while they were written to, so only
+	// the dead ones are taken out
+	c.mu.Lock()
+	var live []*ringBuff
+	for _, rb := range c.tees {
+		if !containsRing(dead, rb) {
+			live = append(live, rb)
+		}
+	}
+	c.tees = live
+	c.mu.Unlock()
+}
+
+func containsRing(rbs []*ringBuff, rb *ringBuff) bool {
+	for _, r := range rbs {
+		if r == rb {
+			return true
+		}
+	}
+	return false
+}
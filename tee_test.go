@@ -0,0 +1,110 @@
+package memnet
+
+import (
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestConnTee(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tee := remote.(*conn).Tee()
+	other := remote.(*conn).Tee()
+
+	input := []byte("fan-out")
+	if _, err := local.Write(input); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	output := make([]byte, len(input))
+	if _, err := io.ReadFull(remote, output); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	teed := make([]byte, len(input))
+	if _, err := io.ReadFull(tee, teed); err != nil {
+		t.Fatalf("could not read from tee: %v", err)
+	}
+
+	if !reflect.DeepEqual(input, output) || !reflect.DeepEqual(input, teed) {
+		t.Fatalf("conn read %q, tee read %q, want %q", output, teed, input)
+	}
+
+	// A closed tee no longer holds up the conn
+	other.Close()
+	last := remote.(*conn).Tee()
+
+	input = []byte("more")
+	if _, err := local.Write(input); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+	local.Close()
+
+	if output, err = ioutil.ReadAll(remote); err != nil || !reflect.DeepEqual(input, output) {
+		t.Fatalf("ioutil.ReadAll(remote) = %q, %v, want %q, nil", output, err, input)
+	}
+
+	for _, r := range []io.Reader{tee, last} {
+		if teed, err = ioutil.ReadAll(r); err != nil || !reflect.DeepEqual(input, teed) {
+			t.Fatalf("ioutil.ReadAll(tee) = %q, %v, want %q, nil", teed, err, input)
+		}
+	}
+}
+
+func TestConnTeeAddedDuringTee(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rc := remote.(*conn)
+
+	write := func(input string) {
+		t.Helper()
+		if _, err := local.Write([]byte(input)); err != nil {
+			t.Fatalf(errWriteLocalConn, err.Error())
+		}
+	}
+
+	// Fill up the first tee, so that the next Read blocks copying to it
+	first := rc.Tee()
+	write("0123456789")
+	if _, err := io.ReadFull(remote, make([]byte, 10)); err != nil {
+		t.Fatalf(errReadRemoteConn, err)
+	}
+
+	write("abc")
+	readCh := doRead(remote, make([]byte, 3))
+	waitWaiters(t, first.(*Buffer).rb, 0, 1)
+
+	second := rc.Tee()
+
+	if _, err := io.ReadFull(first, make([]byte, 13)); err != nil {
+		t.Fatalf("first.Read = _, %v", err)
+	}
+	if result := <-readCh; result.n != 3 || result.err != nil {
+		t.Fatalf("remote.Read = %d, %v, want 3, nil", result.n, result.err)
+	}
+
+	// The tee registered meanwhile is still there
+	rc.mu.Lock()
+	n := len(rc.tees)
+	rc.mu.Unlock()
+	if n != 2 {
+		t.Fatalf("the conn has %d tees, want 2", n)
+	}
+
+	write("def")
+	if _, err := io.ReadFull(remote, make([]byte, 3)); err != nil {
+		t.Fatalf(errReadRemoteConn, err)
+	}
+
+	output := make([]byte, 3)
+	if _, err := io.ReadFull(second, output); err != nil || string(output) != "def" {
+		t.Fatalf("second.Read = %q, %v, want %q, nil", output, err, "def")
+	}
+}
@@ -0,0 +1,63 @@
+package memnet
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// WithTimeout returns a view of c which fails every Read and Write taking
+// longer than d with a timeout error, like a deadline renewed before each
+// call. Unlike a deadline it leaves c alone, so c can be shared with code
+// which sets deadlines of its own, and those still apply through the
+// view. A d <= 0 returns c itself.
+//
+// The deadlines of conns which don't come from this package can't be
+// left alone: the view sets them before each call and clears them after.
+func WithTimeout(c net.Conn, d time.Duration) net.Conn {
+	if d <= 0 {
+		return c
+	}
+	return &timeoutConn{c, d}
+}
+
+type timeoutConn struct {
+	net.Conn
+	d time.Duration
+}
+
+func (tc *timeoutConn) Read(p []byte) (int, error) {
+	mc, ok := tc.Conn.(*conn)
+	if !ok {
+		tc.Conn.SetReadDeadline(time.Now().Add(tc.d))
+		defer tc.Conn.SetReadDeadline(time.Time{})
+		return tc.Conn.Read(p)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tc.d)
+	defer cancel()
+
+	n, err := mc.readCtx(ctx, p)
+	if err == context.DeadlineExceeded {
+		err = errTimeout
+	}
+	return n, err
+}
+
+func (tc *timeoutConn) Write(p []byte) (int, error) {
+	mc, ok := tc.Conn.(*conn)
+	if !ok {
+		tc.Conn.SetWriteDeadline(time.Now().Add(tc.d))
+		defer tc.Conn.SetWriteDeadline(time.Time{})
+		return tc.Conn.Write(p)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tc.d)
+	defer cancel()
+
+	n, err := mc.writeCtx(ctx, p)
+	if err == context.DeadlineExceeded {
+		err = errTimeout
+	}
+	return n, err
+}
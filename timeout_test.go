@@ -0,0 +1,55 @@
+package memnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithTimeout(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tc := WithTimeout(remote, 20*time.Millisecond)
+
+	start := time.Now()
+	if _, err := tc.Read(make([]byte, 1)); err != errTimeout {
+		t.Fatalf("tc.Read = _, %v, want %v", err, errTimeout)
+	}
+
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Fatalf("tc.Read timed out after %v, want >= %v", d, 20*time.Millisecond)
+	}
+
+	// The conn underneath kept no deadline
+	readCh := doRead(remote, make([]byte, 1))
+	select {
+	case res := <-readCh:
+		t.Fatalf("remote.Read = _, %v, want it to block", res.err)
+	case <-time.After(40 * time.Millisecond):
+	}
+
+	if _, err := local.Write([]byte("a")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if res := <-readCh; res.err != nil {
+		t.Fatalf(errReadRemoteConn, res.err)
+	}
+
+	// A deadline of the conn underneath still applies to the view
+	remote.SetReadDeadline(time.Now().Add(-time.Second))
+	if _, err := WithTimeout(remote, time.Hour).Read(make([]byte, 1)); err != errTimeout {
+		t.Fatalf("tc.Read = _, %v, want %v", err, errTimeout)
+	}
+
+	// Fill the peer's buffer up so the write has to wait
+	if _, err := tc.Write(make([]byte, dLnOptn.t)); err != nil {
+		t.Fatalf("tc.Write = _, %v", err)
+	}
+
+	if _, err := tc.Write([]byte("b")); err != errTimeout {
+		t.Fatalf("tc.Write = _, %v, want %v", err, errTimeout)
+	}
+}
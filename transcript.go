@@ -0,0 +1,117 @@
+package memnet
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// transcriptLimit is the number of bytes kept in the transcript of a
+// conn, the oldest entries are dropped past it.
+const transcriptLimit = 1 << 20
+
+// transcriptConns is the number of conns a listener keeps the transcripts
+// of, those of the oldest conns are dropped past it.
+const transcriptConns = 64
+
+// WithTranscript makes the listener record every read and write of its
+// conns, which shows what went over the wire when a protocol test
+// fails. The records are fetched with Transcript and outlive the conns,
+// the listener keeps those of its last 64 conns.
+func WithTranscript() Option {
+	return func(l *Listener) {
+		l.transcripts = make(map[uint64]*transcript)
+	}
+}
+
+// TranscriptEntry is a read or a write recorded by WithTranscript.
+type TranscriptEntry struct {
+	Time time.Time
+
+	// Dialer tells whether the dialing or the accepted end did the
+	// read or write
+	Dialer bool
+
+	// Write tells writes from reads
+	Write bool
+
+	// Data is what was read or written, before compression
+	Data []byte
+}
+
+// transcript is the record of the reads and writes of a conn. A nil
+// *transcript records nothing.
+type transcript struct {
+	mu      sync.Mutex
+	entries []TranscriptEntry
+	size    int
+}
+
+// record appends a read or write of b.
+func (t *transcript) record(dialer, write bool, b []byte) {
+	if t == nil || len(b) == 0 {
+		return
+	}
+
+	e := TranscriptEntry{time.Now(), dialer, write, append([]byte(nil), b...)}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.entries = append(t.entries, e)
+	t.size += len(b)
+
+	for t.size > transcriptLimit && len(t.entries) > 1 {
+		t.size -= len(t.entries[0].Data)
+		t.entries = t.entries[1:]
+	}
+}
+
+// newTranscript starts the transcript of the pair with id, it is nil
+// unless WithTranscript is set.
+func (l *Listener) newTranscript(id uint64) *transcript {
+	if l.transcripts == nil {
+		return nil
+	}
+
+	t := &transcript{}
+
+	l.mu.Lock()
+	l.transcripts[id] = t
+	l.transcriptIDs = append(l.transcriptIDs, id)
+	if len(l.transcriptIDs) > transcriptConns {
+		delete(l.transcripts, l.transcriptIDs[0])
+		l.transcriptIDs = l.transcriptIDs[1:]
+	}
+	l.mu.Unlock()
+	return t
+}
+
+// Transcript returns the reads and writes of the conn with id in the
+// order they happened, see ConnID. It is nil for unknown ids and unless
+// WithTranscript is set.
+func (l *Listener) Transcript(id uint64) []TranscriptEntry {
+	l.mu.Lock()
+	t := l.transcripts[id]
+	l.mu.Unlock()
+
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]TranscriptEntry(nil), t.entries...)
+}
+
+// ConnID returns the id of the memnet connection behind c, both ends
+// of a connection share it. Conns are looked through wrappers like for
+// SamePeer.
+func ConnID(c net.Conn) (uint64, bool) {
+	mc, ok := unwrapConn(c)
+	if !ok {
+		return 0, false
+	}
+	return mc.pair.id, true
+}
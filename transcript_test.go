@@ -0,0 +1,168 @@
+package memnet
+
+import (
+	"io"
+	"testing"
+)
+
+func TestTranscript(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, WithTranscript())
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	exchange := func(from, to io.ReadWriter, msg string) {
+		t.Helper()
+		if _, err := from.Write([]byte(msg)); err != nil {
+			t.Fatalf(errWriteLocalConn, err.Error())
+		}
+
+		output := make([]byte, len(msg))
+		if _, err := io.ReadFull(to, output); err != nil {
+			t.Fatalf(errReadRemoteConn, err.Error())
+		}
+	}
+
+	exchange(local, remote, "HELO")
+	exchange(remote, local, "250 OK")
+	local.Close()
+
+	id, ok := ConnID(remote)
+	if lid, _ := ConnID(local); !ok || lid != id {
+		t.Fatalf("ConnID(remote) = %d, %v, want %d, true", id, ok, lid)
+	}
+
+	want := []TranscriptEntry{
+		{Dialer: true, Write: true, Data: []byte("HELO")},
+		{Dialer: false, Write: false, Data: []byte("HELO")},
+		{Dialer: false, Write: true, Data: []byte("250 OK")},
+		{Dialer: true, Write: false, Data: []byte("250 OK")},
+	}
+
+	got := ln.Transcript(id)
+	if len(got) != len(want) {
+		t.Fatalf("len(ln.Transcript) = %d, want %d", len(got), len(want))
+	}
+
+	for i, e := range got {
+		w := want[i]
+		if e.Dialer != w.Dialer || e.Write != w.Write || string(e.Data) != string(w.Data) {
+			t.Fatalf("ln.Transcript[%d] = %v %v %q, want %v %v %q",
+				i, e.Dialer, e.Write, e.Data, w.Dialer, w.Write, w.Data)
+		}
+
+		if i > 0 && e.Time.Before(got[i-1].Time) {
+			t.Fatalf("ln.Transcript[%d] happened before the entry ahead of it", i)
+		}
+	}
+
+	if ln.Transcript(id+1) != nil {
+		t.Fatalf("ln.Transcript of an unknown id isn't nil")
+	}
+}
+
+func TestTranscriptLimit(t *testing.T) {
+	tr := &transcript{}
+	chunk := make([]byte, transcriptLimit/4)
+	for i := 0; i < 6; i++ {
+		tr.record(true, true, chunk)
+	}
+
+	if len(tr.entries) != 4 || tr.size != transcriptLimit {
+		t.Fatalf("transcript has %d entries of %d bytes, want %d of %d",
+			len(tr.entries), tr.size, 4, transcriptLimit)
+	}
+}
+
+func TestTranscriptConns(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, WithTranscript())
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	var ids []uint64
+	for i := 0; i < transcriptConns+1; i++ {
+		local, err := ln.Dial()
+		if err != nil {
+			t.Fatalf(errMemServer, err.Error())
+		}
+
+		if _, err := ln.Accept(); err != nil {
+			t.Fatalf(errAcceptMemConn, err.Error())
+		}
+
+		if _, err := local.Write([]byte("x")); err != nil {
+			t.Fatalf(errWriteLocalConn, err.Error())
+		}
+		local.Close()
+
+		id, _ := ConnID(local)
+		ids = append(ids, id)
+	}
+
+	// Only the transcripts of the latest conns are kept
+	if entries := ln.Transcript(ids[0]); entries != nil {
+		t.Fatalf("Transcript of the oldest conn = %v, want nil", entries)
+	}
+
+	for _, id := range ids[1:] {
+		if entries := ln.Transcript(id); len(entries) != 1 {
+			t.Fatalf("Transcript(%d) has %d entries, want 1", id, len(entries))
+		}
+	}
+}
+
+func TestTranscriptDiscardN(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, WithTranscript())
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	if _, err := local.Write([]byte("headerBODY")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if n, err := remote.(*conn).DiscardN(6); n != 6 || err != nil {
+		t.Fatalf("DiscardN = %d, %v, want 6, nil", n, err)
+	}
+
+	if _, err := io.ReadFull(remote, make([]byte, 4)); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	// The skipped bytes were read as far as the transcript goes
+	id, _ := ConnID(remote)
+	var read []byte
+	for _, e := range ln.Transcript(id) {
+		if !e.Write {
+			read = append(read, e.Data...)
+		}
+	}
+
+	if string(read) != "headerBODY" {
+		t.Fatalf("transcript reads = %q, want %q", read, "headerBODY")
+	}
+}
@@ -0,0 +1,64 @@
+package memnet
+
+import (
+	"testing"
+	"time"
+)
+
+// waiters reports how many goroutines are blocked waiting for the ring
+// to get readable and writable.
+func (rb *ringBuff) waiters() (readers, writers int) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	return rb.rdwaiters, rb.wrwaiters
+}
+
+// waitWaiters polls rb till its waiter counts match, as the blocked
+// goroutines get there asynchronously.
+func waitWaiters(t *testing.T, rb *ringBuff, readers, writers int) {
+	t.Helper()
+
+	var rd, wr int
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		if rd, wr = rb.waiters(); rd == readers && wr == writers {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("waiters = %d, %d, want %d, %d", rd, wr, readers, writers)
+}
+
+func TestRingBuffWaiters(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	rb := remote.(*conn).r.(*ringBuff)
+	waitWaiters(t, rb, 0, 0)
+
+	done := doRead(remote, make([]byte, 1))
+	waitWaiters(t, rb, 1, 0)
+
+	if _, err := local.Write([]byte("a")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	if res := <-done; res.err != nil {
+		t.Fatalf(errReadRemoteConn, res.err)
+	}
+	waitWaiters(t, rb, 0, 0)
+
+	// Fill the ring up and block a writer on it
+	if _, err := local.Write(make([]byte, dLnOptn.t)); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	wdone := doWrite(local, []byte("b"))
+	waitWaiters(t, rb, 0, 1)
+
+	remote.Close()
+	<-wdone
+	waitWaiters(t, rb, 0, 0)
+}
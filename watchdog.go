@@ -0,0 +1,67 @@
+package memnet
+
+import "time"
+
+// WithConsumerWatchdog calls onStall with the ConnID of a conn whose
+// peer sent bytes that went unread for longer than d, which flags a
+// stuck reader. The countdown starts when bytes arrive in a drained
+// buffer and restarts with every read which leaves some unread. It fires
+// once per stall, onStall runs on a goroutine of its own.
+func WithConsumerWatchdog(d time.Duration, onStall func(connID uint64)) Option {
+	return func(l *Listener) {
+		l.watchdogD, l.onStall = d, onStall
+	}
+}
+
+// watchdog is the countdown of WithConsumerWatchdog for one ring, it is
+// guarded by the ring's mutex. gen tells the current timer from the ones
+// stopped too late.
+type watchdog struct {
+	d       time.Duration
+	onStall func(id uint64)
+	id      uint64
+	timer   stopper
+	gen     uint64
+}
+
+// watchdogWrote starts the countdown once bytes arrived in a drained
+// ring. rb.mu must be held.
+func (rb *ringBuff) watchdogWrote() {
+	if rb.wd != nil && rb.wd.timer == nil {
+		rb.armWatchdog()
+	}
+}
+
+// watchdogRead restarts the countdown after a read, or stops it if the
+// read drained the ring. rb.mu must be held.
+func (rb *ringBuff) watchdogRead() {
+	wd := rb.wd
+	if wd == nil {
+		return
+	}
+
+	if wd.timer != nil {
+		wd.timer.Stop()
+		wd.timer = nil
+	}
+
+	if rb.unread() > 0 {
+		rb.armWatchdog()
+	}
+}
+
+func (rb *ringBuff) armWatchdog() {
+	wd := rb.wd
+	wd.gen++
+	gen := wd.gen
+
+	wd.timer = rb.clock.AfterFunc(wd.d, func() {
+		rb.mu.Lock()
+		stalled := wd.gen == gen && !rb.closed && rb.unread() > 0
+		rb.mu.Unlock()
+
+		if stalled {
+			wd.onStall(wd.id)
+		}
+	})
+}
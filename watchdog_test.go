@@ -0,0 +1,71 @@
+package memnet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConsumerWatchdog(t *testing.T) {
+	clk := &fakeClock{}
+
+	var stalls []uint64
+	onStall := func(id uint64) { stalls = append(stalls, id) }
+
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a,
+		withClock(clk), WithConsumerWatchdog(time.Second, onStall))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	local, err := ln.Dial()
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	check := func(want int) {
+		t.Helper()
+		if len(stalls) != want {
+			t.Fatalf("onStall ran %d times, want %d", len(stalls), want)
+		}
+	}
+
+	if _, err := local.Write([]byte("abcd")); err != nil {
+		t.Fatalf(errWriteLocalConn, err.Error())
+	}
+
+	clk.Advance(600 * time.Millisecond)
+	check(0)
+
+	// A read restarts the countdown
+	if _, err := remote.Read(make([]byte, 2)); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	clk.Advance(600 * time.Millisecond)
+	check(0)
+
+	clk.Advance(400 * time.Millisecond)
+	check(1)
+
+	if id, _ := ConnID(remote); stalls[0] != id {
+		t.Fatalf("onStall(%d), want the conn's id %d", stalls[0], id)
+	}
+
+	// Once per stall
+	clk.Advance(5 * time.Second)
+	check(1)
+
+	// A drained buffer isn't stalled
+	if _, err := remote.Read(make([]byte, 2)); err != nil {
+		t.Fatalf(errReadRemoteConn, err.Error())
+	}
+
+	clk.Advance(5 * time.Second)
+	check(1)
+}
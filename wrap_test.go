@@ -0,0 +1,57 @@
+package memnet
+
+import "testing"
+
+// wrapped reports whether the unread bytes of the ring wrap around the
+// end of buff, so that they are read in two parts.
+func (rb *ringBuff) wrapped() bool {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	return !rb.empty() && rb.w > 0 && rb.w <= rb.r
+}
+
+func TestRingBuffWrapped(t *testing.T) {
+	local, remote, err := memConnServe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	rb := remote.(*conn).r.(*ringBuff)
+	if rb.wrapped() {
+		t.Fatal("rb.wrapped() = true for an empty ring")
+	}
+
+	write := func(input string) {
+		t.Helper()
+		if n, err := local.Write([]byte(input)); n != len(input) || err != nil {
+			t.Fatalf("local.Write(%q) = %d, %v", input, n, err)
+		}
+	}
+
+	read := func(want string) {
+		t.Helper()
+		output := make([]byte, len(want))
+		if n, err := remote.Read(output); n != len(want) || err != nil || string(output) != want {
+			t.Fatalf("remote.Read = %d, %v, %q, want %d, nil, %q", n, err, output[:n], len(want), want)
+		}
+	}
+
+	// The ring holds dLnOptn.t = 10 bytes
+	write("abcdefgh")
+	read("abcdef")
+	if rb.wrapped() {
+		t.Fatal("rb.wrapped() = true before the writes reached the end")
+	}
+
+	write("ijkl")
+	if !rb.wrapped() {
+		t.Fatal("rb.wrapped() = false after the writes wrapped around")
+	}
+
+	read("ghij")
+	if rb.wrapped() {
+		t.Fatal("rb.wrapped() = true after the reads wrapped around")
+	}
+	read("kl")
+}
@@ -0,0 +1,47 @@
+package memnet
+
+import "context"
+
+// WithWriterQueue lets up to n writers wait on a conn dialed with
+// WithBufferSize(0) to be served in the order they arrived by the
+// successive reads of the peer. The writers past n wait for a place in
+// the queue, in no particular order. Without it the waiting writers of
+// such a conn go in no particular order.
+func WithWriterQueue(n int) Option {
+	return func(l *Listener) {
+		l.writerQueue = n
+	}
+}
+
+// joinWriters waits for a place in the writer queue of the ring and
+// returns the ticket of the write in it, or 0 if the ring has no
+// queue. rb.mu must be held.
+func (rb *ringBuff) joinWriters(ctx context.Context) (uint64, error) {
+	if rb.wrqueue <= 0 {
+		return 0, nil
+	}
+
+	for len(rb.wrline.tickets) >= rb.wrqueue {
+		if rb.closed || rb.writeClosed {
+			return 0, rb.errClosedPipe()
+		}
+
+		if rb.wrtimeout {
+			return 0, errTimeout
+		}
+
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		rb.wait(&rb.wrwait, rb.wrid)
+	}
+
+	return rb.wrline.join(), nil
+}
+
+// writerTurn reports whether the write with ticket is the next to be
+// served, a ticket of 0 is always.
+func (rb *ringBuff) writerTurn(ticket uint64) bool {
+	return ticket == 0 || rb.wrline.first(ticket)
+}
@@ -0,0 +1,45 @@
+package memnet
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWriterQueue(t *testing.T) {
+	ln, err := Listen(dLnOptn.c, dLnOptn.t, dLnOptn.a, WithWriterQueue(3))
+	if err != nil {
+		t.Fatalf(errMemListener, err.Error())
+	}
+	defer ln.Close()
+
+	local, err := ln.DialContext(context.Background(), WithBufferSize(0))
+	if err != nil {
+		t.Fatalf(errMemServer, err.Error())
+	}
+
+	remote, err := ln.Accept()
+	if err != nil {
+		t.Fatalf(errAcceptMemConn, err.Error())
+	}
+
+	// Park the writers one by one so that their order is known
+	rb := local.(*conn).w.(*ringBuff)
+	payloads := []string{"first", "second", "third"}
+
+	var writeChs []<-chan ioResult
+	for i, p := range payloads {
+		writeChs = append(writeChs, doWrite(local, []byte(p)))
+		waitWaiters(t, rb, 0, i+1)
+	}
+
+	for i, want := range payloads {
+		output := make([]byte, len(want))
+		if n, err := remote.Read(output); n != len(want) || err != nil || string(output) != want {
+			t.Fatalf("remote.Read = %d, %v, %q, want %d, nil, %q", n, err, output[:n], len(want), want)
+		}
+
+		if result := <-writeChs[i]; result.n != len(want) || result.err != nil {
+			t.Fatalf("local.Write(%q) = %d, %v, want %d, nil", want, result.n, result.err, len(want))
+		}
+	}
+}